@@ -2,28 +2,50 @@ package probe
 
 import (
 	"context"
-	"os"
-	"os/exec"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
 
-	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-plugin"
 	"github.com/linyows/probe/pb"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 var (
 	BuiltinCmd = "builtin-actions"
 	Handshake  = plugin.HandshakeConfig{ProtocolVersion: 1, MagicCookieKey: "probe", MagicCookieValue: "actions"}
 	PluginMap  = map[string]plugin.Plugin{"actions": &ActionsPlugin{}}
+	// VersionedPluginMap is the plugins of each protocol version, and the
+	// newest version supported by both sides is used
+	VersionedPluginMap = map[int]plugin.PluginSet{
+		1: PluginMap,
+		2: {"actions": &ActionsV2Plugin{}},
+	}
 )
 
 type ActionsArgs []string
 type ActionsParams map[string]string
 
+// Actions is the interface of actions in protocol version 1, which receive
+// and return flattened strings.
 type Actions interface {
 	Run(args []string, with map[string]string) (map[string]string, error)
 }
 
+// ActionsV2 is the interface of actions in protocol version 2, which keep
+// the types of values such as numbers, bools, lists and maps. Numbers are
+// passed as float64 and integral ones are converted to int, so integers
+// beyond 2^53 lose precision.
+type ActionsV2 interface {
+	Run(args []string, with map[string]any) (map[string]any, error)
+}
+
 type ActionsPlugin struct {
 	plugin.Plugin
 	Impl Actions
@@ -52,8 +74,11 @@ func (m *ActionsClient) Run(args []string, with map[string]string) (map[string]s
 	if err != nil {
 		return res, err
 	}
+	if runRes.Error != "" {
+		return res, decodeActionError(runRes.Error)
+	}
 
-	return runRes.Result, err
+	return runRes.Result, nil
 }
 
 type ActionsServer struct {
@@ -62,48 +87,323 @@ type ActionsServer struct {
 
 func (m *ActionsServer) Run(ctx context.Context, req *pb.RunRequest) (*pb.RunResponse, error) {
 	v, err := m.Impl.Run(req.Args, req.With)
-	return &pb.RunResponse{Result: v}, err
+	if err != nil {
+		return &pb.RunResponse{Error: encodeActionError(ClassifyError(err))}, nil
+	}
+	return &pb.RunResponse{Result: v}, nil
+}
+
+type ActionsV2Plugin struct {
+	plugin.Plugin
+	Impl ActionsV2
+}
+
+func (p *ActionsV2Plugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
+	pb.RegisterActionsV2Server(s, &ActionsV2Server{Impl: p.Impl})
+	return nil
+}
+
+func (p *ActionsV2Plugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (any, error) {
+	return &ActionsV2Client{client: pb.NewActionsV2Client(c)}, nil
+}
+
+// StreamingActions are v2 actions reporting progress while running. The
+// progress func is safe to call from multiple goroutines.
+type StreamingActions interface {
+	ActionsV2
+	RunStream(args []string, with map[string]any, progress func(Progress)) (map[string]any, error)
+}
+
+// ContextActions are v2 actions canceled by the context of the run, such as
+// the deadline of Run.
+type ContextActions interface {
+	ActionsV2
+	RunContext(ctx context.Context, args []string, with map[string]any, progress func(Progress)) (map[string]any, error)
+}
+
+// Progress is reported by actions while running, such as messages sent,
+// bytes, partial results or log lines.
+type Progress struct {
+	Message string         `json:"message,omitempty"`
+	Count   int64          `json:"count,omitempty"`
+	Total   int64          `json:"total,omitempty"`
+	Bytes   int64          `json:"bytes,omitempty"`
+	Partial map[string]any `json:"partial,omitempty"`
+}
+
+func (p Progress) String() string {
+	var details []string
+	switch {
+	case p.Total > 0:
+		details = append(details, fmt.Sprintf("%d/%d", p.Count, p.Total))
+	case p.Count > 0:
+		details = append(details, fmt.Sprintf("%d", p.Count))
+	}
+	if p.Bytes > 0 {
+		details = append(details, fmt.Sprintf("%d bytes", p.Bytes))
+	}
+	if len(details) == 0 {
+		return p.Message
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s (%s)", p.Message, strings.Join(details, ", ")))
+}
+
+type ActionsV2Client struct {
+	client pb.ActionsV2Client
+	// action selects the action of an ActionServer
+	action string
 }
 
-func RunActions(name string, args []string, with map[string]any, verbose bool) (map[string]any, error) {
-	loglevel := hclog.Warn
-	if verbose {
-		loglevel = hclog.Debug
+// outgoing returns the context of requests having the action
+func (m *ActionsV2Client) outgoing(ctx context.Context) context.Context {
+	if m.action == "" {
+		return ctx
 	}
+	return metadata.AppendToOutgoingContext(ctx, ActionMetadataKey, m.action)
+}
+
+func (m *ActionsV2Client) Run(args []string, with map[string]any) (map[string]any, error) {
+	return m.run(context.Background(), args, with)
+}
 
-	log := hclog.New(&hclog.LoggerOptions{
-		Name:   "actions",
-		Output: os.Stdout,
-		Level:  loglevel,
+func (m *ActionsV2Client) run(ctx context.Context, args []string, with map[string]any) (map[string]any, error) {
+	s, err := NewStruct(with)
+	if err != nil {
+		return nil, err
+	}
+
+	runRes, err := m.client.Run(m.outgoing(ctx), &pb.RunV2Request{
+		Args: args,
+		With: s,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return runResult(runRes)
+}
+
+// RunStream runs the action with the progress reported, and actions without
+// the streaming RPC are run by Run.
+func (m *ActionsV2Client) RunStream(args []string, with map[string]any, progress func(Progress)) (map[string]any, error) {
+	return m.RunContext(context.Background(), args, with, progress)
+}
+
+// RunContext runs the action as RunStream, and the RPC is canceled by ctx.
+func (m *ActionsV2Client) RunContext(ctx context.Context, args []string, with map[string]any, progress func(Progress)) (map[string]any, error) {
+	s, err := NewStruct(with)
+	if err != nil {
+		return nil, err
+	}
 
-	cl := plugin.NewClient(&plugin.ClientConfig{
-		HandshakeConfig:  Handshake,
-		Plugins:          PluginMap,
-		Cmd:              exec.Command(os.Args[0], BuiltinCmd, name),
-		AllowedProtocols: []plugin.Protocol{plugin.ProtocolNetRPC, plugin.ProtocolGRPC},
-		Logger:           log,
+	stream, err := m.client.RunStream(m.outgoing(ctx), &pb.RunV2Request{
+		Args: args,
+		With: s,
 	})
-	defer cl.Kill()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		ev, err := stream.Recv()
+		if status.Code(err) == codes.Unimplemented {
+			return m.run(ctx, args, with)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch e := ev.Event.(type) {
+		case *pb.RunEvent_Progress:
+			if progress == nil {
+				continue
+			}
+			p := Progress{
+				Message: e.Progress.Message,
+				Count:   e.Progress.Count,
+				Total:   e.Progress.Total,
+				Bytes:   e.Progress.Bytes,
+			}
+			if e.Progress.Partial != nil {
+				p.Partial = StructToMap(e.Progress.Partial)
+			}
+			progress(p)
+		case *pb.RunEvent_Response:
+			return runResult(e.Response)
+		}
+	}
+}
 
-	protocol, err := cl.Client()
+// Describe returns the manifest of the action, and nil for actions without
+// manifests.
+func (m *ActionsV2Client) Describe() (*Manifest, error) {
+	res, err := m.client.Describe(m.outgoing(context.Background()), &pb.DescribeRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
+	return manifestFromPB(res), nil
+}
+
+func runResult(res *pb.RunV2Response) (map[string]any, error) {
+	if e := res.Error; e != nil {
+		return nil, &ActionError{Category: ErrorCategory(e.Category), Message: e.Message, Retryable: e.Retryable}
+	}
+	return StructToMap(res.Result), nil
+}
+
+type ActionsV2Server struct {
+	Impl ActionsV2
+}
+
+func (m *ActionsV2Server) Run(ctx context.Context, req *pb.RunV2Request) (*pb.RunV2Response, error) {
+	if impl, ok := m.Impl.(ContextActions); ok {
+		return runResponse(impl.RunContext(ctx, req.Args, StructToMap(req.With), nil)), nil
+	}
+	return runResponse(m.Impl.Run(req.Args, StructToMap(req.With))), nil
+}
+
+// RunStream sends the progress of streaming actions, and the response at
+// the end.
+func (m *ActionsV2Server) RunStream(req *pb.RunV2Request, stream pb.ActionsV2_RunStreamServer) error {
+	var mu sync.Mutex
+	var res *pb.RunV2Response
+	switch impl := m.Impl.(type) {
+	case ContextActions:
+		// canceled when the client cancels the run
+		res = runResponse(impl.RunContext(stream.Context(), req.Args, StructToMap(req.With), streamProgress(stream, &mu)))
+	case StreamingActions:
+		res = runResponse(impl.RunStream(req.Args, StructToMap(req.With), streamProgress(stream, &mu)))
+	default:
+		res = runResponse(m.Impl.Run(req.Args, StructToMap(req.With)))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return stream.Send(&pb.RunEvent{Event: &pb.RunEvent_Response{Response: res}})
+}
+
+// streamProgress returns the progress func sending the events to the
+// stream, and the sends are serialized by mu.
+func streamProgress(stream pb.ActionsV2_RunStreamServer, mu *sync.Mutex) func(Progress) {
+	return func(p Progress) {
+		var partial *structpb.Struct
+		if p.Partial != nil {
+			// partial results that can't be encoded are dropped
+			partial, _ = NewStruct(p.Partial)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		// errors are returned by the response
+		_ = stream.Send(&pb.RunEvent{Event: &pb.RunEvent_Progress{Progress: &pb.Progress{
+			Message: p.Message,
+			Count:   p.Count,
+			Total:   p.Total,
+			Bytes:   p.Bytes,
+			Partial: partial,
+		}}})
+	}
+}
+
+// Describe returns the manifest of actions implementing ManifestActions
+func (m *ActionsV2Server) Describe(ctx context.Context, req *pb.DescribeRequest) (*pb.Manifest, error) {
+	impl, ok := m.Impl.(ManifestActions)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the action has no manifest")
+	}
+	return impl.Manifest().toPB(), nil
+}
+
+func runResponse(v map[string]any, err error) *pb.RunV2Response {
+	if err != nil {
+		return &pb.RunV2Response{Error: pbActionError(err)}
+	}
+
+	s, err := NewStruct(v)
+	if err != nil {
+		return &pb.RunV2Response{Error: pbActionError(err)}
+	}
+
+	return &pb.RunV2Response{Result: s}
+}
 
-	raw, err := protocol.Dispense("actions")
+func pbActionError(err error) *pb.ActionError {
+	e := ClassifyError(err)
+	return &pb.ActionError{Category: string(e.Category), Message: e.Message, Retryable: e.Retryable}
+}
+
+// VersionedPlugins returns the plugins serving the v2 actions for every
+// protocol version, so hosts of v1 can also run them.
+func VersionedPlugins(impl ActionsV2) map[int]plugin.PluginSet {
+	return map[int]plugin.PluginSet{
+		1: {"actions": &ActionsPlugin{Impl: &shimV2{impl: impl}}},
+		2: {"actions": &ActionsV2Plugin{Impl: impl}},
+	}
+}
+
+// shimV1 runs v1 actions with typed values by flattening them
+type shimV1 struct {
+	impl Actions
+}
+
+func (s *shimV1) Run(args []string, with map[string]any) (map[string]any, error) {
+	result, err := s.impl.Run(args, FlattenInterface(with))
 	if err != nil {
 		return nil, err
 	}
+	return UnflattenInterface(result), nil
+}
+
+// shimV2 runs v2 actions with flattened strings from hosts of v1
+type shimV2 struct {
+	impl ActionsV2
+}
 
-	actions := raw.(Actions)
+func (s *shimV2) Run(args []string, with map[string]string) (map[string]string, error) {
+	result, err := s.impl.Run(args, UnflattenInterface(with))
+	if err != nil {
+		return map[string]string{}, err
+	}
+	return FlattenInterface(result), nil
+}
 
-	flatW := FlattenInterface(with)
-	result, err := actions.Run(args, flatW)
+// NewStruct converts a map to a protobuf struct through JSON, so values of
+// any type that JSON can encode are accepted.
+func NewStruct(m map[string]any) (*structpb.Struct, error) {
+	b, err := json.Marshal(m)
 	if err != nil {
 		return nil, err
 	}
-	unflatR := UnflattenInterface(result)
+	var v map[string]any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(v)
+}
 
-	return unflatR, nil
+// StructToMap converts a protobuf struct to a map. Numbers of protobuf
+// structs are all doubles and received as float64, typed params of actions
+// convert them to the types of their fields.
+func StructToMap(s *structpb.Struct) map[string]any {
+	return s.AsMap()
+}
+
+// RunActions runs the action in a new plugin process, which is killed after
+// the run. Use ActionPool to reuse the processes.
+func RunActions(name string, args []string, with map[string]any, out io.Writer, verbose bool) (map[string]any, error) {
+	pool := NewActionPool(out, verbose)
+	defer pool.Close()
+
+	return pool.Run(context.Background(), name, args, with)
+}
+
+// dispensedActions returns the actions of the negotiated protocol version,
+// v1 actions are run through the shim.
+func dispensedActions(raw any) ActionsV2 {
+	if a, ok := raw.(Actions); ok {
+		return &shimV1{impl: a}
+	}
+	return raw.(ActionsV2)
 }
@@ -0,0 +1,20 @@
+// Package actions registers the builtin actions to run in the process of
+// probe instead of plugin processes.
+package actions
+
+import (
+	"github.com/linyows/probe"
+	"github.com/linyows/probe/actions/hello"
+	"github.com/linyows/probe/actions/http"
+	"github.com/linyows/probe/actions/smtp"
+	"github.com/linyows/probe/sdk"
+)
+
+// Builtin returns the registry of the builtin actions
+func Builtin(opts ...sdk.Option) *probe.Registry {
+	r := probe.NewRegistry()
+	r.Register("hello", hello.New(opts...))
+	r.Register("http", http.New(opts...))
+	r.Register("smtp", smtp.New(opts...))
+	return r
+}
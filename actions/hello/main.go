@@ -1,36 +1,25 @@
 package hello
 
 import (
-	"os"
+	"context"
 
-	"github.com/hashicorp/go-hclog"
-	"github.com/hashicorp/go-plugin"
-	"github.com/linyows/probe"
+	"github.com/linyows/probe/sdk"
 )
 
-type Action struct {
-	log hclog.Logger
-}
-
-func (a *Action) Run(args []string, with map[string]string) (map[string]string, error) {
-	a.log.Info("Hello!")
+func Run(ctx context.Context, with map[string]any) (map[string]any, error) {
+	sdk.Logger(ctx).Info("Hello!")
 	return with, nil
 }
 
-func Serve() {
-	log := hclog.New(&hclog.LoggerOptions{
-		Level:      hclog.Info,
-		Output:     os.Stderr,
-		JSONFormat: true,
-	})
+func Serve(opts ...sdk.Option) {
+	sdk.Serve("hello", Run, options(opts)...)
+}
 
-	pl := &probe.ActionsPlugin{
-		Impl: &Action{log: log},
-	}
+// New returns the hello action to run in the process
+func New(opts ...sdk.Option) *sdk.Action[map[string]any, map[string]any] {
+	return sdk.New("hello", Run, options(opts)...)
+}
 
-	plugin.Serve(&plugin.ServeConfig{
-		HandshakeConfig: probe.Handshake,
-		Plugins:         map[string]plugin.Plugin{"actions": pl},
-		GRPCServer:      plugin.DefaultGRPCServer,
-	})
+func options(opts []sdk.Option) []sdk.Option {
+	return append([]sdk.Option{sdk.Description("Log hello and return the params as outputs")}, opts...)
 }
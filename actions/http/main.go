@@ -1,20 +1,18 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	hp "net/http"
 	"net/url"
-	"os"
 	"path"
-	"strconv"
 	"strings"
 
-	"github.com/hashicorp/go-hclog"
-	"github.com/hashicorp/go-plugin"
 	"github.com/linyows/probe"
 	"github.com/linyows/probe/http"
+	"github.com/linyows/probe/sdk"
 )
 
 var httpMethods = []string{
@@ -29,58 +27,75 @@ var httpMethods = []string{
 	hp.MethodTrace,
 }
 
-type Action struct {
-	log hclog.Logger
-}
-
-func (a *Action) Run(args []string, with map[string]string) (map[string]string, error) {
-	a.log.Debug(fmt.Sprintf("received: %#v", with))
+func Run(ctx context.Context, with map[string]any) (map[string]any, error) {
+	log := sdk.Logger(ctx)
 
 	if err := updateMap(with); err != nil {
-		return map[string]string{}, err
+		return map[string]any{}, probe.NewActionError(probe.ErrorValidation, err)
 	}
 
-	a.log.Debug(fmt.Sprintf("updated: %#v", with))
+	log.Debug(fmt.Sprintf("updated: %#v", with))
 
 	before := http.WithBefore(func(req *hp.Request) {
-		a.log.Debug(fmt.Sprintf("http.Request: %#v", req))
+		log.Debug(fmt.Sprintf("http.Request: %#v", req))
 	})
 	after := http.WithAfter(func(res *hp.Response) {
-		a.log.Debug(fmt.Sprintf("http.Response: %#v", res))
+		log.Debug(fmt.Sprintf("http.Response: %#v", res))
 	})
-	ret, err := http.Request(with, before, after)
 
-	a.log.Debug(fmt.Sprintf("return: %#v", ret))
-	a.log.Debug(fmt.Sprintf("error: %#v", err))
+	return http.Send(with, http.WithContext(ctx), before, after)
+}
+
+func Serve(opts ...sdk.Option) {
+	sdk.Serve("http", Run, options(opts)...)
+}
 
-	return ret, err
+// New returns the http action to run in the process
+func New(opts ...sdk.Option) *sdk.Action[map[string]any, map[string]any] {
+	return sdk.New("http", Run, options(opts)...)
 }
 
-func Serve() {
-	log := hclog.New(&hclog.LoggerOptions{
-		Level:      hclog.Debug,
-		Output:     os.Stderr,
-		JSONFormat: true,
-	})
+func options(opts []sdk.Option) []sdk.Option {
+	m := Manifest()
+	return append([]sdk.Option{
+		sdk.Description(m.Description),
+		sdk.Params(m.Params...),
+		sdk.Outputs(m.Outputs...),
+	}, opts...)
+}
 
-	pl := &probe.ActionsPlugin{
-		Impl: &Action{log: log},
+// Manifest returns the manifest of the http action, whose params are the
+// request and the routes of methods such as `get: /path`.
+func Manifest() probe.Manifest {
+	params := probe.ParamsOf(http.Req{})
+	for i := range params {
+		if params[i].Name == "body" {
+			// maps are encoded as JSON or forms
+			params[i].Type = "any"
+		}
+	}
+	for _, method := range httpMethods {
+		params = append(params, probe.Param{
+			Name:        strings.ToLower(method),
+			Type:        "string",
+			Description: fmt.Sprintf("Path joined to the url with the method %s", method),
+		})
 	}
 
-	plugin.Serve(&plugin.ServeConfig{
-		HandshakeConfig: probe.Handshake,
-		Plugins:         map[string]plugin.Plugin{"actions": pl},
-		GRPCServer:      plugin.DefaultGRPCServer,
-	})
+	return probe.Manifest{
+		Name:        "http",
+		Description: "Send an HTTP request",
+		Params:      params,
+		Outputs:     probe.FieldsOf(http.Result{}),
+	}
 }
 
-func updateMap(data map[string]string) error {
+func updateMap(data map[string]any) error {
 	var err error
 	if err = replaceMethodAndURL(data); err != nil {
 		return err
 	}
-	v, exists := data["headers__content-type"]
-	if exists && v == "application/json" {
+	if contentType(data) == "application/json" {
 		if err = convertBodyToJson(data); err != nil {
 			return err
 		}
@@ -93,8 +108,19 @@ func updateMap(data map[string]string) error {
 	return nil
 }
 
+// contentType returns the content-type of the headers
+func contentType(data map[string]any) string {
+	headers, _ := data["headers"].(map[string]any)
+	for k, v := range headers {
+		if strings.EqualFold(k, "content-type") {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
 // replace `get: /foo/bar` and `url: http://localhost:8000` to `method: GET` and `url: http://localhost:8000/foo/bar`
-func replaceMethodAndURL(data map[string]string) error {
+func replaceMethodAndURL(data map[string]any) error {
 	for _, method := range httpMethods {
 		lowerMethod := strings.ToLower(method)
 		route, ok := data[lowerMethod]
@@ -106,7 +132,7 @@ func replaceMethodAndURL(data map[string]string) error {
 		delete(data, lowerMethod)
 
 		// get the base-url from url
-		baseURL, ok := data["url"]
+		baseURL, ok := data["url"].(string)
 		if !ok {
 			return errors.New("Error: url is missing in the map")
 		}
@@ -116,7 +142,7 @@ func replaceMethodAndURL(data map[string]string) error {
 		if err != nil {
 			return err
 		}
-		u.Path = path.Join(u.Path, route)
+		u.Path = path.Join(u.Path, fmt.Sprint(route))
 		data["url"] = u.String()
 
 		break
@@ -125,25 +151,12 @@ func replaceMethodAndURL(data map[string]string) error {
 	return nil
 }
 
-func convertBodyToJson(data map[string]string) error {
-	values := map[string]any{}
-
-	for key, value := range data {
-		if strings.HasPrefix(key, "body__") {
-			newKey := strings.TrimPrefix(key, "body__")
-			// If it is a numeric string, set the type to number
-			num, err := strconv.Atoi(value)
-			if err == nil {
-				values[newKey] = num
-			} else {
-				values[newKey] = value
-			}
-			delete(data, key)
-		}
-	}
-
-	if len(values) > 0 {
-		j, err := json.Marshal(values)
+// convertBodyToJson encodes the body of a map or a list as JSON, and the
+// types of values are kept.
+func convertBodyToJson(data map[string]any) error {
+	switch body := data["body"].(type) {
+	case map[string]any, []any:
+		j, err := json.Marshal(body)
 		if err != nil {
 			return err
 		}
@@ -153,21 +166,27 @@ func convertBodyToJson(data map[string]string) error {
 	return nil
 }
 
-func convertBodyToTextWithContentType(data map[string]string) error {
-	values := url.Values{}
-
-	for key, value := range data {
-		if strings.HasPrefix(key, "body__") {
-			newKey := strings.TrimPrefix(key, "body__")
-			values.Add(newKey, value)
-			delete(data, key)
+func convertBodyToTextWithContentType(data map[string]any) error {
+	body, ok := data["body"].(map[string]any)
+	if !ok {
+		if v, exists := data["body"]; exists && v != nil {
+			data["body"] = fmt.Sprint(v)
 		}
+		return nil
+	}
+
+	values := url.Values{}
+	for key, value := range body {
+		values.Add(key, fmt.Sprint(value))
 	}
+	data["body"] = values.Encode()
 
-	if len(values) > 0 {
-		data["body"] = values.Encode()
-		data["headers__content-type"] = "application/x-www-form-urlencoded"
+	headers, ok := data["headers"].(map[string]any)
+	if !ok {
+		headers = map[string]any{}
+		data["headers"] = headers
 	}
+	headers["content-type"] = "application/x-www-form-urlencoded"
 
 	return nil
 }
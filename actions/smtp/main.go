@@ -1,48 +1,36 @@
 package smtp
 
 import (
-	"os"
+	"context"
 
-	"github.com/hashicorp/go-hclog"
-	"github.com/hashicorp/go-plugin"
 	"github.com/linyows/probe"
 	"github.com/linyows/probe/mail"
+	"github.com/linyows/probe/sdk"
 )
 
-type Action struct {
-	log hclog.Logger
-}
-
-func (a *Action) Run(args []string, with map[string]string) (map[string]string, error) {
-	var b mail.Bulk
-	var result = map[string]string{}
-	if err := probe.AssignStruct(with, &b); err != nil {
-		return result, err
+// Run delivers the messages until ctx is canceled, and reports the progress
+// every time a message is sent. Errors of the sessions are classified as
+// connection, timeout or tls errors.
+func Run(ctx context.Context, b *mail.Bulk) (map[string]any, error) {
+	b.OnSent = func(sent, bytes int) {
+		sdk.Report(ctx, probe.Progress{Message: "sent", Count: int64(sent), Total: int64(b.Message), Bytes: int64(bytes)})
 	}
-
-	m, err := mail.NewBulk(with)
-	if err != nil {
-		return result, err
+	if err := b.DeliverContext(ctx); err != nil {
+		return map[string]any{}, probe.ClassifyError(err)
 	}
-	m.Deliver()
 
-	return result, nil
+	return map[string]any{}, nil
 }
 
-func Serve() {
-	log := hclog.New(&hclog.LoggerOptions{
-		Level:      hclog.Info,
-		Output:     os.Stderr,
-		JSONFormat: true,
-	})
+func Serve(opts ...sdk.Option) {
+	sdk.Serve("smtp", Run, options(opts)...)
+}
 
-	pl := &probe.ActionsPlugin{
-		Impl: &Action{log: log},
-	}
+// New returns the smtp action to run in the process
+func New(opts ...sdk.Option) *sdk.Action[*mail.Bulk, map[string]any] {
+	return sdk.New("smtp", Run, options(opts)...)
+}
 
-	plugin.Serve(&plugin.ServeConfig{
-		HandshakeConfig: probe.Handshake,
-		Plugins:         map[string]plugin.Plugin{"actions": pl},
-		GRPCServer:      plugin.DefaultGRPCServer,
-	})
+func options(opts []sdk.Option) []sdk.Option {
+	return append([]sdk.Option{sdk.Description("Send messages to an SMTP server in bulk")}, opts...)
 }
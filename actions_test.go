@@ -0,0 +1,109 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewStructAndStructToMap(t *testing.T) {
+	with := map[string]any{
+		"zip":     "01234",
+		"id":      "123",
+		"count":   3,
+		"ratio":   0.5,
+		"whole":   2.0,
+		"enabled": true,
+		"tags":    []any{"a", 1},
+		"headers": map[string]string{"accept": "application/json"},
+		"none":    nil,
+	}
+
+	s, err := NewStruct(with)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := StructToMap(s)
+
+	// numbers are received as float64
+	expects := map[string]any{
+		"zip":     "01234",
+		"id":      "123",
+		"count":   float64(3),
+		"ratio":   0.5,
+		"whole":   float64(2),
+		"enabled": true,
+		"tags":    []any{"a", float64(1)},
+		"headers": map[string]any{"accept": "application/json"},
+		"none":    nil,
+	}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+}
+
+type echoActions struct {
+	with map[string]string
+}
+
+func (e *echoActions) Run(args []string, with map[string]string) (map[string]string, error) {
+	e.with = with
+	return with, nil
+}
+
+func TestShimV1(t *testing.T) {
+	impl := &echoActions{}
+	got, err := dispensedActions(impl).Run(nil, map[string]any{
+		"body": map[string]any{"name": "alice", "age": 20},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flat := map[string]string{"body__name": "alice", "body__age": "20"}
+	if !reflect.DeepEqual(impl.with, flat) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", flat, impl.with)
+	}
+
+	expects := map[string]any{"body": map[string]any{"name": "alice", "age": 20}}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+}
+
+type typedEchoActions struct{}
+
+func (typedEchoActions) Run(args []string, with map[string]any) (map[string]any, error) {
+	return with, nil
+}
+
+func TestShimV2(t *testing.T) {
+	plugins := VersionedPlugins(typedEchoActions{})
+	v1 := plugins[1]["actions"].(*ActionsPlugin).Impl
+
+	with := map[string]string{"headers__accept": "*/*", "code": "200"}
+	got, err := v1.Run(nil, with)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, with) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", with, got)
+	}
+}
+
+func TestProgressString(t *testing.T) {
+	tests := []struct {
+		progress Progress
+		expects  string
+	}{
+		{Progress{Message: "connected"}, "connected"},
+		{Progress{Message: "sent", Count: 2, Total: 5, Bytes: 330}, "sent (2/5, 330 bytes)"},
+		{Progress{Message: "received", Count: 3}, "received (3)"},
+		{Progress{Bytes: 10}, "(10 bytes)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.progress.String(); got != tt.expects {
+			t.Errorf("\nExpected:\n%s\nGot:\n%s", tt.expects, got)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/linyows/probe"
+)
+
+// actions runs the subcommands of actions: list and describe
+func (c *Cmd) actions() int {
+	sub := ""
+	if len(c.Paths) > 0 {
+		sub = c.Paths[0]
+	}
+
+	switch {
+	case sub == "list":
+		return c.listActions()
+	case sub == "describe" && len(c.Paths) == 2:
+		return c.describeAction(c.Paths[1])
+	default:
+		fmt.Println("Usage: probe actions list | probe actions describe <name>")
+		return 1
+	}
+}
+
+func (c *Cmd) listActions() int {
+	pool := probe.NewActionPool(io.Discard, false)
+	defer pool.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tDESCRIPTION\tPATH")
+	for _, a := range probe.ListActions() {
+		path := a.Path
+		if path == "" {
+			path = "(builtin)"
+		}
+		ver, desc := "-", "-"
+		m, err := pool.Manifest(a.Name)
+		switch {
+		case err != nil:
+			msg, _, _ := strings.Cut(err.Error(), "\n")
+			desc = fmt.Sprintf("error: %s", msg)
+		case m != nil:
+			ver, desc = orDash(m.Version), orDash(m.Description)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", a.Name, ver, desc, path)
+	}
+
+	if err := w.Flush(); err != nil {
+		fmt.Printf("%s\n", err)
+		return 1
+	}
+	return 0
+}
+
+func (c *Cmd) describeAction(name string) int {
+	pool := probe.NewActionPool(io.Discard, false)
+	defer pool.Close()
+
+	m, err := pool.Manifest(name)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return 1
+	}
+	if m == nil {
+		fmt.Printf("%s has no manifest\n", name)
+		return 1
+	}
+
+	fmt.Printf("%s %s\n", m.Name, m.Version)
+	if m.Description != "" {
+		fmt.Printf("  %s\n", m.Description)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if len(m.Params) > 0 {
+		fmt.Fprintln(w, "\nParams:")
+		for _, p := range m.Params {
+			var attrs []string
+			if p.Required {
+				attrs = append(attrs, "required")
+			}
+			if p.Default != nil {
+				attrs = append(attrs, fmt.Sprintf("default: %v", p.Default))
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", p.Name, p.Type, strings.Join(attrs, ", "), p.Description)
+		}
+	}
+	if len(m.Outputs) > 0 {
+		fmt.Fprintln(w, "\nOutputs:")
+		for _, f := range m.Outputs {
+			fmt.Fprintf(w, "  %s\t%s\t\t%s\n", f.Name, f.Type, f.Description)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		fmt.Printf("%s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// lint checks the workflows by the manifests of the actions
+func (c *Cmd) lint() int {
+	patterns := c.Paths
+	if c.Command != "" && c.Command != "lint" {
+		// `--lint` takes the paths after it
+		patterns = append([]string{c.Command}, patterns...)
+	}
+	if c.WorkflowPath != "" {
+		patterns = append([]string{c.WorkflowPath}, patterns...)
+	}
+	paths, err := probe.FindWorkflows(patterns)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return 1
+	}
+	if len(paths) == 0 {
+		fmt.Println("No workflow specified")
+		fmt.Println("try --help to know more")
+		return 1
+	}
+
+	status := 0
+	for _, path := range paths {
+		issues, err := probe.New(path, false).Lint()
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			status = 1
+			continue
+		}
+		for _, issue := range issues {
+			fmt.Printf("%s: %s\n", path, issue)
+			status = 1
+		}
+	}
+
+	return status
+}
+
+// withSchemas returns the schemas of `with` by the manifests of the actions,
+// and actions failing to describe themselves are skipped.
+func withSchemas() map[string]map[string]any {
+	pool := probe.NewActionPool(io.Discard, false)
+	defer pool.Close()
+
+	withs := map[string]map[string]any{}
+	for _, a := range probe.ListActions() {
+		m, err := pool.Manifest(a.Name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", a.Name, err)
+			continue
+		}
+		if m != nil {
+			withs[a.Name] = m.WithSchema()
+		}
+	}
+
+	return withs
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
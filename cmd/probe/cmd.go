@@ -1,35 +1,63 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"maps"
+	"os"
 	"strings"
 
 	"github.com/linyows/probe"
+	"github.com/linyows/probe/actions"
 	"github.com/linyows/probe/actions/hello"
 	http "github.com/linyows/probe/actions/http"
 	"github.com/linyows/probe/actions/smtp"
+	"github.com/linyows/probe/sdk"
 )
 
+const secretsPassphraseEnv = "PROBE_SECRETS_PASSPHRASE"
+
 type Cmd struct {
+	Command      string
+	Paths        []string
 	WorkflowPath string
 	Init         bool
 	Lint         bool
 	Help         bool
 	Verbose      bool
+	Parallel     int
+	Report       string
+	ReportFile   string
+	Tags         stringList
+	SkipTags     stringList
+	Jobs         stringList
+	EnvFiles     stringList
+	Vars         stringList
+	VarsFiles    stringList
+	SecretsFile  string
+	SecretsKey   string
+	UpdateSnaps  bool
+	InProcess    bool
+	Listen       string
+	TLSCert      string
+	TLSKey       string
+	TLSClientCA  string
 	validFlags   []string
 	ver          string
 	rev          string
 }
 
 func runBuiltinActions(name string) {
+	ver := sdk.Version(version)
 	switch name {
 	case "http":
-		http.Serve()
+		http.Serve(ver)
 	case "hello":
-		hello.Serve()
+		hello.Serve(ver)
 	case "smtp":
-		smtp.Serve()
+		smtp.Serve(ver)
 	}
 }
 
@@ -40,7 +68,7 @@ func newCmd(args []string) *Cmd {
 	}
 
 	c := Cmd{
-		validFlags: []string{"help", "init", "lint", "workflow", "verbose"},
+		validFlags: []string{"help", "init", "lint", "workflow", "verbose", "parallel", "report", "report-file", "tags", "skip-tags", "job", "env-file", "var", "vars-file", "secrets-file", "secrets-key", "update-snapshots", "in-process", "listen", "tls-cert", "tls-key", "tls-client-ca"},
 		ver:        version,
 		rev:        commit,
 	}
@@ -48,8 +76,25 @@ func newCmd(args []string) *Cmd {
 	flag.StringVar(&c.WorkflowPath, "workflow", "", "Specify yaml-path of workflow")
 	flag.BoolVar(&c.Help, "help", false, "Show command usage")
 	flag.BoolVar(&c.Init, "init", false, "Export a workflow template as yaml file")
-	flag.BoolVar(&c.Lint, "lint", false, "Check the syntax in workflow")
+	flag.BoolVar(&c.Lint, "lint", false, "Check the workflows, same as the lint command")
 	flag.BoolVar(&c.Verbose, "verbose", false, "Show verbose log")
+	flag.IntVar(&c.Parallel, "parallel", 1, "Number of workflows to run in parallel")
+	flag.StringVar(&c.Report, "report", "", "Write a report of all workflows: text or json")
+	flag.StringVar(&c.ReportFile, "report-file", "", "Specify path of the report instead of stdout")
+	flag.Var(&c.Tags, "tags", "Run only jobs and steps having any of the comma-separated tags")
+	flag.Var(&c.SkipTags, "skip-tags", "Skip jobs and steps having any of the comma-separated tags")
+	flag.Var(&c.Jobs, "job", "Run only jobs whose name matches the glob (repeatable)")
+	flag.Var(&c.EnvFiles, "env-file", "Load env from the dotenv file (repeatable)")
+	flag.Var(&c.Vars, "var", "Set a variable as key=value (repeatable)")
+	flag.Var(&c.VarsFiles, "vars-file", "Load variables from the yaml file (repeatable)")
+	flag.StringVar(&c.SecretsFile, "secrets-file", "", "Load secrets from the age encrypted yaml file")
+	flag.StringVar(&c.SecretsKey, "secrets-key", "", "Specify the age key file to decrypt secrets, or set "+secretsPassphraseEnv)
+	flag.BoolVar(&c.UpdateSnaps, "update-snapshots", false, "Rewrite the snapshot files with the responses")
+	flag.BoolVar(&c.InProcess, "in-process", false, "Run the builtin actions in the process instead of plugins")
+	flag.StringVar(&c.Listen, "listen", defaultListen, "Address of action-server as host:port or unix:///path/to.sock")
+	flag.StringVar(&c.TLSCert, "tls-cert", "", "Certificate file of action-server")
+	flag.StringVar(&c.TLSKey, "tls-key", "", "Key file of action-server")
+	flag.StringVar(&c.TLSClientCA, "tls-client-ca", "", "CA file verifying client certificates of action-server (mTLS)")
 
 	for _, arg := range args[1:] {
 		if strings.HasPrefix(arg, "-") && !c.isValid(arg) {
@@ -59,10 +104,89 @@ func newCmd(args []string) *Cmd {
 		}
 	}
 
-	flag.Parse()
+	// flags are allowed before and after the command and paths
+	rest := args[1:]
+	for {
+		if err := flag.CommandLine.Parse(rest); err != nil {
+			return nil
+		}
+		rest = flag.Args()
+		if len(rest) == 0 {
+			break
+		}
+		if c.Command == "" {
+			c.Command = rest[0]
+		} else {
+			c.Paths = append(c.Paths, rest[0])
+		}
+		rest = rest[1:]
+	}
+
 	return &c
 }
 
+// loadVars returns env and vars by the precedence:
+//
+//	env:  process env < --env-file (in order)
+//	vars: --vars-file (in order) < --var
+func (c *Cmd) loadVars() (map[string]string, map[string]any, error) {
+	env, err := probe.LoadEnvFiles(c.EnvFiles...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vars, err := probe.LoadVarsFiles(c.VarsFiles...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	over, err := probe.ParseVars(c.Vars)
+	if err != nil {
+		return nil, nil, err
+	}
+	maps.Copy(vars, over)
+
+	return env, vars, nil
+}
+
+func (c *Cmd) loadSecrets() (map[string]any, error) {
+	if c.SecretsFile == "" {
+		return nil, nil
+	}
+
+	ids, err := probe.SecretsIdentities(c.SecretsKey, os.Getenv(secretsPassphraseEnv))
+	if err != nil {
+		return nil, err
+	}
+
+	return probe.LoadSecretsFile(c.SecretsFile, ids...)
+}
+
+// stringList is a repeatable flag
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// split returns values separated by commas
+func (s stringList) split() []string {
+	var values []string
+	for _, v := range s {
+		for _, vv := range strings.Split(v, ",") {
+			if vv = strings.TrimSpace(vv); vv != "" {
+				values = append(values, vv)
+			}
+		}
+	}
+	return values
+}
+
 func (c *Cmd) isValid(flag string) bool {
 	if idx := strings.Index(flag, "="); idx != -1 {
 		flag = flag[:idx]
@@ -82,25 +206,130 @@ func (c *Cmd) usage() {
 Probe - scenario testing tool (ver: %s [%s])
 
 Usage: probe [options] <command>
+
+Commands:
+  run                      Run workflows of files, directories or globs
+  lint                     Check the params of steps by the action manifests
+  schema                   Print the JSON Schema of workflow
+  actions list             List the builtin and external actions
+  actions describe <name>  Print the manifest of the action
+  action-server [names]    Serve the actions over gRPC for remote runners
+
+Options:
 `
 	h = strings.TrimPrefix(h, "\n")
 	fmt.Fprint(flag.CommandLine.Output(), fmt.Sprintf(h, c.ver, c.rev))
+	flag.PrintDefaults()
+}
+
+func (c *Cmd) schema() int {
+	s := probe.JSONSchema(withSchemas())
+	s["$comment"] = fmt.Sprintf("Generated by probe %s (%s)", c.ver, c.rev)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		fmt.Printf("%s\n", err)
+		return 1
+	}
+
+	return 0
 }
 
 func (c *Cmd) start() int {
 	switch {
 	case c.Help:
 		c.usage()
-	case c.Lint:
+	case c.Command == "schema":
+		return c.schema()
+	case c.Command == "actions":
+		return c.actions()
+	case c.Command == "action-server":
+		return c.actionServer()
+	case c.Lint || c.Command == "lint":
+		return c.lint()
 	case c.Init:
+	case c.Command == "run" || c.Command == "":
+		return c.run()
 	default:
-		p := probe.New(c.WorkflowPath, c.Verbose)
-		if err := p.Do(); err != nil {
-			fmt.Printf("%#v\n", err)
-		} else {
-			return p.ExitStatus()
-		}
+		fmt.Printf("Unknown command: %s\n", c.Command)
+		fmt.Println("try --help to know more")
 	}
 
 	return 1
 }
+
+func (c *Cmd) run() int {
+	paths := c.Paths
+	if c.WorkflowPath != "" {
+		paths = append([]string{c.WorkflowPath}, paths...)
+	}
+	if len(paths) == 0 {
+		fmt.Println("No workflow specified")
+		fmt.Println("try --help to know more")
+		return 1
+	}
+
+	rep, ok := probe.Reporters["text"]
+	if c.Report != "" {
+		if rep, ok = probe.Reporters[c.Report]; !ok {
+			fmt.Printf("Unknown report format: %s\n", c.Report)
+			return 1
+		}
+	}
+
+	env, vars, err := c.loadVars()
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return 1
+	}
+
+	secrets, err := c.loadSecrets()
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return 1
+	}
+
+	s := probe.NewSuite(paths, c.Verbose)
+	s.Env = env
+	s.Vars = vars
+	s.Secrets = secrets
+	s.Parallel = c.Parallel
+	s.UpdateSnapshots = c.UpdateSnaps
+	if c.InProcess {
+		s.Registry = actions.Builtin(sdk.Version(version))
+	}
+	s.Filter = probe.Filter{
+		Tags:     c.Tags.split(),
+		SkipTags: c.SkipTags.split(),
+		Jobs:     c.Jobs,
+	}
+	report, err := s.Do()
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return 1
+	}
+
+	// the summary is shown by default only for multiple workflows
+	if c.Report == "" && len(report.Workflows) < 2 {
+		return report.ExitStatus()
+	}
+
+	out := io.Writer(os.Stdout)
+	if c.ReportFile != "" {
+		f, err := os.Create(c.ReportFile)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := rep.Report(out, report); err != nil {
+		fmt.Printf("%s\n", err)
+		return 1
+	}
+
+	return report.ExitStatus()
+}
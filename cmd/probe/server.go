@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/linyows/probe"
+	"github.com/linyows/probe/actions"
+	"github.com/linyows/probe/sdk"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const defaultListen = "127.0.0.1:7878"
+
+// actionServer serves the actions of the args, or the builtin actions, for
+// remote runners until it is interrupted.
+func (c *Cmd) actionServer() int {
+	names := c.Paths
+	if len(names) == 0 {
+		names = probe.BuiltinActions
+	}
+
+	lis, err := listen(c.Listen)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return 1
+	}
+
+	var opts []grpc.ServerOption
+	if c.TLSCert != "" || c.TLSKey != "" {
+		cfg, err := probe.ServerTLSConfig(c.TLSCert, c.TLSKey, c.TLSClientCA)
+		if err != nil {
+			lis.Close()
+			fmt.Printf("%s\n", err)
+			return 1
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg)))
+	} else if c.TLSClientCA != "" {
+		lis.Close()
+		fmt.Println("--tls-client-ca requires --tls-cert and --tls-key")
+		return 1
+	}
+
+	s := &probe.ActionServer{
+		Actions:  names,
+		Registry: actions.Builtin(sdk.Version(version)),
+		Log:      os.Stderr,
+		Verbose:  c.Verbose,
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		s.Close()
+	}()
+
+	fmt.Printf("Serving %s on %s\n", strings.Join(names, ", "), lis.Addr())
+	if err := s.Serve(lis, opts...); err != nil {
+		fmt.Printf("%s\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// listen listens on unix:///path/to.sock, tcp://host:port or host:port
+func listen(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix://"); ok {
+		return net.Listen("unix", path)
+	}
+	return net.Listen("tcp", strings.TrimPrefix(addr, "tcp://"))
+}
@@ -0,0 +1,158 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/goccy/go-yaml"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// inlineSchemaName is the name of inline schemas, $ref in them are
+// resolved from the directory of the workflow.
+const inlineSchemaName = "inline-schema.json"
+
+// SchemaViolation is a violation of JSON Schema at the JSON pointer
+type SchemaViolation struct {
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+func (v SchemaViolation) String() string {
+	return fmt.Sprintf("%s: %s", v.Pointer, v.Message)
+}
+
+// ValidateSchema validates v with JSON Schema, which is a path of a JSON or
+// YAML file relative to dir, or an inline schema.
+func ValidateSchema(schema any, dir string, v any) ([]SchemaViolation, error) {
+	s, err := compileSchema(schema, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Validate(v)
+	if err == nil {
+		return nil, nil
+	}
+
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return nil, err
+	}
+
+	// sorted since the order of properties is random
+	violations := schemaViolations(verr)
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Pointer < violations[j].Pointer })
+
+	return violations, nil
+}
+
+func compileSchema(schema any, dir string) (*jsonschema.Schema, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c := jsonschema.NewCompiler()
+	c.LoadURL = loadSchemaURL
+
+	var path string
+	switch s := schema.(type) {
+	case string:
+		path = s
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(abs, path)
+		}
+
+	case map[string]any:
+		path = filepath.Join(abs, inlineSchemaName)
+		b, err := json.Marshal(s)
+		if err != nil {
+			return nil, fmt.Errorf("schema: %w", err)
+		}
+		if err := c.AddResource(path, bytes.NewReader(b)); err != nil {
+			return nil, fmt.Errorf("schema: %w", err)
+		}
+
+	default:
+		return nil, fmt.Errorf("schema must be a file path or a map: %v", schema)
+	}
+
+	compiled, err := c.Compile(path)
+	if err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+
+	return compiled, nil
+}
+
+// loadSchemaURL loads schemas of files also in YAML
+func loadSchemaURL(s string) (io.ReadCloser, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "file" {
+		return jsonschema.LoadURL(s)
+	}
+
+	b, err := os.ReadFile(u.Path)
+	if err != nil {
+		return nil, err
+	}
+	if ext := filepath.Ext(u.Path); ext == ".yml" || ext == ".yaml" {
+		if b, err = yaml.YAMLToJSON(b); err != nil {
+			return nil, fmt.Errorf("%s: %w", u.Path, err)
+		}
+	}
+
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+// schemaViolations returns the leaves of the validation errors
+func schemaViolations(e *jsonschema.ValidationError) []SchemaViolation {
+	if len(e.Causes) == 0 {
+		return []SchemaViolation{{Pointer: "#" + e.InstanceLocation, Message: e.Message}}
+	}
+
+	var res []SchemaViolation
+	for _, c := range e.Causes {
+		res = append(res, schemaViolations(c)...)
+	}
+	return res
+}
+
+// schemaResult returns the result of validation for expressions as res.schema
+func schemaResult(violations []SchemaViolation) map[string]any {
+	errs := make([]any, 0, len(violations))
+	for _, v := range violations {
+		errs = append(errs, map[string]any{"pointer": v.Pointer, "message": v.Message})
+	}
+	return map[string]any{
+		"valid":  len(violations) == 0,
+		"errors": errs,
+	}
+}
+
+func schemaMessage(violations []SchemaViolation) string {
+	msgs := make([]string, 0, len(violations))
+	for _, v := range violations {
+		msgs = append(msgs, "schema "+v.String())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func printViolations(w io.Writer, violations []SchemaViolation, indent string) {
+	for _, v := range violations {
+		fmt.Fprintf(w, "%s%s schema %s\n", indent, color.RedString("✘"), v)
+	}
+}
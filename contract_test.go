@@ -0,0 +1,59 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  any
+		body    any
+		expects []SchemaViolation
+	}{
+		{
+			name:   "valid",
+			schema: "user.json",
+			body:   map[string]any{"id": float64(1), "name": "alice", "tags": []any{"admin"}},
+		},
+		{
+			name:   "violations",
+			schema: "user.json",
+			body:   map[string]any{"id": "1", "tags": []any{""}},
+			expects: []SchemaViolation{
+				{Pointer: "#", Message: "missing properties: 'name'"},
+				{Pointer: "#/id", Message: "expected integer, but got string"},
+				{Pointer: "#/tags/0", Message: "length must be >= 1, but got 0"},
+			},
+		},
+		{
+			name:   "inline",
+			schema: map[string]any{"type": "object", "required": []any{"id"}},
+			body:   "not json",
+			expects: []SchemaViolation{
+				{Pointer: "#", Message: "expected object, but got string"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateSchema(tt.schema, "testdata/schema", tt.body)
+			if err != nil {
+				t.Fatalf("ValidateSchema error %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.expects) {
+				t.Errorf("\nExpected:\n%#v\nGot:\n%#v", tt.expects, got)
+			}
+		})
+	}
+}
+
+func TestValidateSchema_Errors(t *testing.T) {
+	for _, schema := range []any{"missing.json", 1} {
+		if _, err := ValidateSchema(schema, "testdata/schema", nil); err == nil {
+			t.Errorf("%v: expected error", schema)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package probe
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
+	"github.com/fatih/color"
+)
+
+// Condition is a failed condition in an expression with the values of
+// the operands, like power-assert.
+type Condition struct {
+	Expr     string    `json:"expr"`
+	Operands []Operand `json:"operands,omitempty"`
+}
+
+type Operand struct {
+	Expr  string `json:"expr"`
+	Value any    `json:"value"`
+}
+
+func (c Condition) String() string {
+	if len(c.Operands) == 0 {
+		return c.Expr
+	}
+	vals := make([]string, 0, len(c.Operands))
+	for _, o := range c.Operands {
+		vals = append(vals, fmt.Sprintf("%s: %s", o.Expr, formatValue(o.Value)))
+	}
+	return fmt.Sprintf("%s (%s)", c.Expr, strings.Join(vals, ", "))
+}
+
+// Diagnose returns the conditions that made the expression false.
+// Conditions joined with `&&` and `||` are examined individually.
+func Diagnose(input string, env any) []Condition {
+	tree, err := parser.Parse(input)
+	if err != nil {
+		return nil
+	}
+	return failedConditions(tree.Node, env)
+}
+
+func failedConditions(node ast.Node, env any) []Condition {
+	if out, err := EvalExpr(node.String(), env); err == nil && out == true {
+		return nil
+	}
+
+	if n, ok := node.(*ast.BinaryNode); ok {
+		switch n.Operator {
+		case "&&", "and", "||", "or":
+			return append(failedConditions(n.Left, env), failedConditions(n.Right, env)...)
+		}
+	}
+
+	c := Condition{Expr: node.String()}
+	for _, o := range operandsOf(node) {
+		var v any
+		out, err := EvalExpr(o.String(), env)
+		if err != nil {
+			v = fmt.Sprintf("error: %s", firstLine(err.Error()))
+		} else {
+			v = out
+		}
+		c.Operands = append(c.Operands, Operand{Expr: o.String(), Value: v})
+	}
+
+	return []Condition{c}
+}
+
+// operandsOf returns the operands whose values are not obvious
+func operandsOf(node ast.Node) []ast.Node {
+	var nodes []ast.Node
+	switch n := node.(type) {
+	case *ast.BinaryNode:
+		nodes = []ast.Node{n.Left, n.Right}
+	case *ast.UnaryNode:
+		nodes = []ast.Node{n.Node}
+	case *ast.CallNode:
+		nodes = n.Arguments
+	case *ast.BuiltinNode:
+		nodes = n.Arguments
+	default:
+		nodes = []ast.Node{node}
+	}
+
+	var res []ast.Node
+	for _, n := range nodes {
+		switch n.(type) {
+		case *ast.NilNode, *ast.IntegerNode, *ast.FloatNode, *ast.BoolNode, *ast.StringNode,
+			*ast.ConstantNode, *ast.ClosureNode:
+			continue
+		}
+		res = append(res, n)
+	}
+
+	return res
+}
+
+func printDiagnosis(w io.Writer, conds []Condition, indent string) {
+	for _, c := range conds {
+		fmt.Fprintf(w, "%s%s %s\n", indent, color.RedString("✘"), c.Expr)
+		printOperands(w, c.Operands, indent+"  ")
+	}
+}
+
+func printOperands(w io.Writer, operands []Operand, indent string) {
+	for _, o := range operands {
+		fmt.Fprintf(w, "%s%s %s\n", indent, color.HiBlackString(o.Expr+":"), formatValue(o.Value))
+	}
+}
+
+func diagnosisMessage(conds []Condition) string {
+	msgs := make([]string, 0, len(conds))
+	for _, c := range conds {
+		msgs = append(msgs, c.String())
+	}
+	return strings.Join(msgs, "\n")
+}
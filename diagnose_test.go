@@ -0,0 +1,61 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiagnose(t *testing.T) {
+	env := TestContext{
+		Res: map[string]any{
+			"code": 200,
+			"body": map[string]any{"message": "hi", "items": []any{1}},
+		},
+	}
+
+	tests := []struct {
+		input   string
+		expects []Condition
+	}{
+		{
+			input: `res.code == 200 && res.body.message == "hello"`,
+			expects: []Condition{
+				{Expr: `res.body.message == "hello"`, Operands: []Operand{{Expr: "res.body.message", Value: "hi"}}},
+			},
+		},
+		{
+			input: `res.code == 201 || len(res.body.items) > 1`,
+			expects: []Condition{
+				{Expr: `res.code == 201`, Operands: []Operand{{Expr: "res.code", Value: 200}}},
+				{Expr: `len(res.body.items) > 1`, Operands: []Operand{{Expr: "len(res.body.items)", Value: 1}}},
+			},
+		},
+		{
+			input: `!(res.code == 200)`,
+			expects: []Condition{
+				{Expr: `!(res.code == 200)`, Operands: []Operand{{Expr: "res.code == 200", Value: true}}},
+			},
+		},
+		{
+			input:   `res.code == 200`,
+			expects: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := Diagnose(tt.input, env)
+			if !reflect.DeepEqual(got, tt.expects) {
+				t.Errorf("\nExpected:\n%#v\nGot:\n%#v", tt.expects, got)
+			}
+		})
+	}
+}
+
+func TestConditionString(t *testing.T) {
+	c := Condition{Expr: `res.body.message == "hello"`, Operands: []Operand{{Expr: "res.body.message", Value: "hi"}}}
+	expects := `res.body.message == "hello" (res.body.message: "hi")`
+	if got := c.String(); got != expects {
+		t.Errorf("\nExpected:\n%s\nGot:\n%s", expects, got)
+	}
+}
@@ -0,0 +1,140 @@
+package probe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/go-plugin"
+)
+
+const (
+	// ActionPrefix is the prefix of executables of external actions
+	ActionPrefix = "probe-action-"
+	// PluginPathEnv is the env of directories searched for external actions
+	PluginPathEnv = "PROBE_PLUGIN_PATH"
+)
+
+var (
+	// BuiltinActions are the actions served by the probe command itself
+	BuiltinActions = []string{"hello", "http", "smtp"}
+	// LocalActionsDir is the directory of actions of the project
+	LocalActionsDir = filepath.Join(".probe", "actions")
+)
+
+// ActionSpec pins the executable of an external action in the workflow:
+//
+//	actions:
+//	  slack:
+//	    sha256: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+type ActionSpec struct {
+	SHA256 string `yaml:"sha256" validate:"omitempty,hexadecimal,len=64"`
+	// CA, Cert and Key are the files of TLS for remote actions of grpcs
+	CA   string `yaml:"ca"`
+	Cert string `yaml:"cert" validate:"required_with=Key"`
+	Key  string `yaml:"key" validate:"required_with=Cert"`
+}
+
+// secureConfig returns the config verifying the checksum of the executable
+func (s ActionSpec) secureConfig() (*plugin.SecureConfig, error) {
+	if s.SHA256 == "" {
+		return nil, nil
+	}
+	sum, err := hex.DecodeString(s.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("sha256 must be hex: %w", err)
+	}
+	return &plugin.SecureConfig{Checksum: sum, Hash: sha256.New()}, nil
+}
+
+// ActionDirs returns the directories searched for external actions in order
+// of ./.probe/actions, $PROBE_PLUGIN_PATH and $PATH.
+func ActionDirs() []string {
+	dirs := []string{LocalActionsDir}
+	dirs = append(dirs, filepath.SplitList(os.Getenv(PluginPathEnv))...)
+	dirs = append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+	return dirs
+}
+
+// FindAction returns the path of the executable of the external action,
+// which is named probe-action-<name>.
+func FindAction(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid action name: %q", name)
+	}
+
+	for _, dir := range ActionDirs() {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, ActionPrefix+name)
+		if isExecutable(path) {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("action %s is not found as %s%s in %s, $%s or $PATH", name, ActionPrefix, name, LocalActionsDir, PluginPathEnv)
+}
+
+// ActionEntry is an action found by ListActions, and Path is empty for the
+// builtin actions.
+type ActionEntry struct {
+	Name string
+	Path string
+}
+
+// ListActions returns the builtin actions and the external actions found in
+// ActionDirs sorted by name. Like FindAction, the first executable of a name
+// is used, and builtin actions are not overridden.
+func ListActions() []ActionEntry {
+	found := map[string]string{}
+	for _, name := range BuiltinActions {
+		found[name] = ""
+	}
+
+	for _, dir := range ActionDirs() {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := strings.CutPrefix(e.Name(), ActionPrefix)
+			if !ok || name == "" {
+				continue
+			}
+			if _, exists := found[name]; exists {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			if isExecutable(path) {
+				found[name] = path
+			}
+		}
+	}
+
+	list := make([]ActionEntry, 0, len(found))
+	for name, path := range found {
+		list = append(list, ActionEntry{Name: name, Path: path})
+	}
+	slices.SortFunc(list, func(a, b ActionEntry) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return list
+}
+
+func isBuiltinAction(name string) bool {
+	return slices.Contains(BuiltinActions, name)
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0
+}
@@ -0,0 +1,108 @@
+package probe
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeAction(t *testing.T, dir, name string, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(dir, ActionPrefix+name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFindAction(t *testing.T) {
+	local := t.TempDir()
+	pluginPath := t.TempDir()
+	path := t.TempDir()
+
+	orig := LocalActionsDir
+	LocalActionsDir = local
+	defer func() { LocalActionsDir = orig }()
+	t.Setenv(PluginPathEnv, pluginPath)
+	t.Setenv("PATH", path)
+
+	writeAction(t, local, "slack", 0755)
+	writeAction(t, pluginPath, "slack", 0755)
+	pinned := writeAction(t, pluginPath, "grpc", 0755)
+	writeAction(t, path, "grpc", 0755)
+	inPath := writeAction(t, path, "ssh", 0755)
+	writeAction(t, local, "noexec", 0644)
+
+	tests := []struct {
+		name    string
+		expects string
+	}{
+		{"slack", filepath.Join(local, ActionPrefix+"slack")},
+		{"grpc", pinned},
+		{"ssh", inPath},
+	}
+	for _, tt := range tests {
+		got, err := FindAction(tt.name)
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if got != tt.expects {
+			t.Errorf("\nExpected:\n%s\nGot:\n%s", tt.expects, got)
+		}
+	}
+
+	for _, name := range []string{"noexec", "missing", "../slack", ""} {
+		if got, err := FindAction(name); err == nil {
+			t.Errorf("%q: expected an error, got %s", name, got)
+		}
+	}
+}
+
+func TestActionSpec_SecureConfig(t *testing.T) {
+	got, err := ActionSpec{}.secureConfig()
+	if err != nil || got != nil {
+		t.Errorf("expected no config, got %#v, %v", got, err)
+	}
+
+	sum := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	got, err = ActionSpec{SHA256: sum}.secureConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Checksum) != 32 || got.Hash == nil {
+		t.Errorf("expected a sha256 config, got %#v", got)
+	}
+
+	if _, err := (ActionSpec{SHA256: "zz"}).secureConfig(); err == nil {
+		t.Error("expected an error of invalid hex")
+	}
+}
+
+func TestListActions(t *testing.T) {
+	local := t.TempDir()
+	path := t.TempDir()
+
+	orig := LocalActionsDir
+	LocalActionsDir = local
+	defer func() { LocalActionsDir = orig }()
+	t.Setenv(PluginPathEnv, "")
+	t.Setenv("PATH", path)
+
+	slack := writeAction(t, local, "slack", 0755)
+	writeAction(t, path, "slack", 0755)
+	writeAction(t, path, "http", 0755)
+	writeAction(t, path, "noexec", 0644)
+
+	got := ListActions()
+	expects := []ActionEntry{
+		{Name: "hello"},
+		{Name: "http"},
+		{Name: "slack", Path: slack},
+		{Name: "smtp"},
+	}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+}
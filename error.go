@@ -1,8 +1,20 @@
 package probe
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"strings"
+	"syscall"
+
+	"github.com/fatih/color"
+	"github.com/goccy/go-yaml"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type ValidationError struct {
@@ -20,3 +32,153 @@ func (e *ValidationError) HasError() bool {
 func (e *ValidationError) AddMessage(s string) {
 	e.messages = append(e.messages, s)
 }
+
+// LoadError is returned when a workflow file can't be decoded or validated.
+// Error() renders the YAML source around the failing position.
+type LoadError struct {
+	Path string
+	Err  error
+}
+
+func (e *LoadError) Error() string {
+	msg := yaml.FormatError(e.Err, !color.NoColor, true)
+	if e.Path == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s:\n%s", e.Path, msg)
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// TemplateError is returned when a template in the value of Key fails
+type TemplateError struct {
+	Key string
+	Err error
+}
+
+func newTemplateError(key string, err error) *TemplateError {
+	if te, ok := err.(*TemplateError); ok {
+		return &TemplateError{Key: key + "." + te.Key, Err: te.Err}
+	}
+	return &TemplateError{Key: key, Err: err}
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCategory is the category of errors of actions
+type ErrorCategory string
+
+const (
+	ErrorConnection ErrorCategory = "connection"
+	ErrorTimeout    ErrorCategory = "timeout"
+	ErrorTLS        ErrorCategory = "tls"
+	ErrorValidation ErrorCategory = "validation"
+	ErrorProtocol   ErrorCategory = "protocol"
+)
+
+// ActionError is an error returned by actions. Retryable reports whether
+// running the action again may succeed.
+type ActionError struct {
+	Category  ErrorCategory `json:"category"`
+	Message   string        `json:"message"`
+	Retryable bool          `json:"retryable"`
+}
+
+func (e *ActionError) Error() string {
+	return fmt.Sprintf("%s error: %s", e.Category, e.Message)
+}
+
+// NewActionError returns an action error of the category, connection and
+// timeout errors are retryable.
+func NewActionError(category ErrorCategory, err error) *ActionError {
+	return &ActionError{
+		Category:  category,
+		Message:   err.Error(),
+		Retryable: category == ErrorConnection || category == ErrorTimeout,
+	}
+}
+
+// ClassifyError returns the action error of err by its type. Errors that
+// can't be classified are protocol errors.
+func ClassifyError(err error) *ActionError {
+	if err == nil {
+		return nil
+	}
+
+	var ae *ActionError
+	if errors.As(err, &ae) {
+		return ae
+	}
+
+	if st, ok := status.FromError(err); ok {
+		switch st.Code() {
+		case codes.Unavailable:
+			return NewActionError(ErrorConnection, errors.New(st.Message()))
+		case codes.DeadlineExceeded:
+			return NewActionError(ErrorTimeout, errors.New(st.Message()))
+		case codes.InvalidArgument:
+			return NewActionError(ErrorValidation, errors.New(st.Message()))
+		default:
+			return NewActionError(ErrorProtocol, errors.New(st.Message()))
+		}
+	}
+
+	var (
+		netErr    net.Error
+		opErr     *net.OpError
+		dnsErr    *net.DNSError
+		valErr    *ValidationError
+		recErr    tls.RecordHeaderError
+		verifyErr *tls.CertificateVerificationError
+		authErr   x509.UnknownAuthorityError
+		hostErr   x509.HostnameError
+		certErr   x509.CertificateInvalidError
+	)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return NewActionError(ErrorTimeout, err)
+	case errors.As(err, &recErr), errors.As(err, &verifyErr), errors.As(err, &authErr),
+		errors.As(err, &hostErr), errors.As(err, &certErr):
+		return NewActionError(ErrorTLS, err)
+	case errors.As(err, &opErr), errors.As(err, &dnsErr), errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET):
+		return NewActionError(ErrorConnection, err)
+	case errors.As(err, &valErr):
+		return NewActionError(ErrorValidation, err)
+	}
+
+	return NewActionError(ErrorProtocol, err)
+}
+
+// encodeActionError returns the action error as JSON for RunResponse.error
+func encodeActionError(e *ActionError) string {
+	if e == nil {
+		return ""
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return e.Message
+	}
+	return string(b)
+}
+
+// decodeActionError returns the action error of RunResponse.error, and a
+// plain message is a protocol error.
+func decodeActionError(s string) *ActionError {
+	if s == "" {
+		return nil
+	}
+	var e ActionError
+	if err := json.Unmarshal([]byte(s), &e); err != nil || e.Category == "" {
+		return &ActionError{Category: ErrorProtocol, Message: s}
+	}
+	return &e
+}
@@ -0,0 +1,73 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		expects *ActionError
+	}{
+		{
+			name:    "action error",
+			err:     fmt.Errorf("wrapped: %w", &ActionError{Category: ErrorValidation, Message: "url is required"}),
+			expects: &ActionError{Category: ErrorValidation, Message: "url is required"},
+		},
+		{
+			name:    "deadline",
+			err:     fmt.Errorf("get: %w", context.DeadlineExceeded),
+			expects: &ActionError{Category: ErrorTimeout, Message: "get: context deadline exceeded", Retryable: true},
+		},
+		{
+			name:    "refused",
+			err:     &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED},
+			expects: &ActionError{Category: ErrorConnection, Message: "dial tcp: connection refused", Retryable: true},
+		},
+		{
+			name:    "grpc unavailable",
+			err:     status.Error(codes.Unavailable, "plugin exited"),
+			expects: &ActionError{Category: ErrorConnection, Message: "plugin exited", Retryable: true},
+		},
+		{
+			name:    "unknown",
+			err:     errors.New("unexpected response"),
+			expects: &ActionError{Category: ErrorProtocol, Message: "unexpected response"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyError(tt.err)
+			if !reflect.DeepEqual(got, tt.expects) {
+				t.Errorf("\nExpected:\n%#v\nGot:\n%#v", tt.expects, got)
+			}
+		})
+	}
+}
+
+func TestDecodeActionError(t *testing.T) {
+	e := &ActionError{Category: ErrorTimeout, Message: "i/o timeout", Retryable: true}
+	if got := decodeActionError(encodeActionError(e)); !reflect.DeepEqual(got, e) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", e, got)
+	}
+
+	expects := &ActionError{Category: ErrorProtocol, Message: "plain message"}
+	if got := decodeActionError("plain message"); !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+
+	if got := decodeActionError(""); got != nil {
+		t.Errorf("expected nil, got %#v", got)
+	}
+}
@@ -0,0 +1,452 @@
+package probe
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/goccy/go-yaml"
+)
+
+// Expectation is an assertion in `expect`, either a boolean expression or
+// a matcher for the value at a path of the response.
+type Expectation struct {
+	Expr    string
+	Path    string
+	Matcher any
+}
+
+// String returns the assertion as written in the workflow
+func (e Expectation) String() string {
+	if e.Path == "" {
+		return e.Expr
+	}
+	return fmt.Sprintf("%s: %s", e.Path, stringify(e.Matcher))
+}
+
+// Expectations is a list of expectations.
+// An item is an expression or a map of paths to matchers:
+//
+//	expect:
+//	- status: 200
+//	- headers.content-type: contains json
+//	- body.items: length >= 1
+//	- res.body.id > 0
+type Expectations []Expectation
+
+func (es *Expectations) UnmarshalYAML(unmarshal func(any) error) error {
+	var items []expectItem
+	if err := unmarshal(&items); err != nil {
+		return err
+	}
+
+	var res Expectations
+	for _, item := range items {
+		res = append(res, item...)
+	}
+	*es = res
+
+	return nil
+}
+
+func (es Expectations) MarshalYAML() (any, error) {
+	items := make([]any, 0, len(es))
+	for _, e := range es {
+		if e.Path == "" {
+			items = append(items, e.Expr)
+		} else {
+			items = append(items, yaml.MapSlice{{Key: e.Path, Value: e.Matcher}})
+		}
+	}
+	return items, nil
+}
+
+// JSONSchema returns the schema of expect for the workflow schema
+func (es Expectations) JSONSchema() map[string]any {
+	return map[string]any{
+		"type": "array",
+		"items": map[string]any{
+			"oneOf": []any{
+				map[string]any{"type": "string"},
+				map[string]any{"type": "object", "minProperties": 1},
+			},
+		},
+	}
+}
+
+// Eval evaluates all expectations with the test context
+func (es Expectations) Eval(env TestContext) []*ExpectResult {
+	results := make([]*ExpectResult, 0, len(es))
+	for _, e := range es {
+		results = append(results, e.Eval(env))
+	}
+	return results
+}
+
+// Eval evaluates the expectation with the test context
+func (e Expectation) Eval(env TestContext) *ExpectResult {
+	r := &ExpectResult{Expect: e.String(), Status: StatusPassed}
+
+	if e.Path == "" {
+		r.Expected = true
+		out, err := EvalExpr(e.Expr, env)
+		switch {
+		case err != nil:
+			r.fail(err.Error())
+		case out != true:
+			r.Actual = out
+			if _, ok := out.(bool); ok {
+				r.Diagnosis = Diagnose(e.Expr, env)
+				r.fail("")
+			} else {
+				r.fail(fmt.Sprintf("expression returned non-boolean: %v", out))
+			}
+		}
+		return r
+	}
+
+	r.Expected = e.Matcher
+	actual, found, err := lookupExpectPath(env.Res, e.Path)
+	if err != nil {
+		r.fail(err.Error())
+		return r
+	}
+	r.Actual = actual
+
+	if !found {
+		r.fail(fmt.Sprintf("%s is not found", e.Path))
+		return r
+	}
+	if e.Matcher == "exists" {
+		return r
+	}
+
+	ok, err := matchValue(actual, e.Matcher)
+	if err != nil {
+		r.fail(err.Error())
+	} else if !ok {
+		r.fail("")
+	}
+
+	return r
+}
+
+// expectItem is an item of expect, which may have multiple pairs
+type expectItem []Expectation
+
+func (item *expectItem) UnmarshalYAML(unmarshal func(any) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		*item = expectItem{{Expr: s}}
+		return nil
+	}
+
+	var pairs yaml.MapSlice
+	if err := unmarshal(&pairs); err != nil {
+		return fmt.Errorf("expect must be an expression or a map of paths and matchers: %w", err)
+	}
+	for _, p := range pairs {
+		*item = append(*item, Expectation{Path: fmt.Sprintf("%v", p.Key), Matcher: p.Value})
+	}
+
+	return nil
+}
+
+// lookupExpectPath returns the value at the path of res.
+// Keys are case-insensitive, and `status` means the status code.
+func lookupExpectPath(res map[string]any, path string) (any, bool, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(steps) > 0 && steps[0].kind == jsonPathKey && steps[0].key == "status" {
+		if _, ok := res["code"]; ok {
+			steps[0].key = "code"
+		}
+	}
+
+	if !isDefiniteJSONPath(steps) {
+		values := selectJSONPath([]any{res}, steps)
+		return values, len(values) > 0, nil
+	}
+
+	var v any = res
+	for _, step := range steps {
+		if step.kind == jsonPathKey {
+			child, ok := jsonChildFold(v, step.key)
+			if !ok {
+				return nil, false, nil
+			}
+			v = child
+			continue
+		}
+		values := selectJSONPathStep(v, step)
+		if len(values) == 0 {
+			return nil, false, nil
+		}
+		v = values[0]
+	}
+
+	return v, true, nil
+}
+
+func jsonChildFold(v any, key string) (any, bool) {
+	if child, ok := jsonChild(v, key); ok {
+		return child, true
+	}
+
+	switch vv := v.(type) {
+	case map[string]any:
+		for k, child := range vv {
+			if strings.EqualFold(k, key) {
+				return child, true
+			}
+		}
+	case map[string]string:
+		for k, child := range vv {
+			if strings.EqualFold(k, key) {
+				return child, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+var compareOperators = []string{"==", "!=", ">=", "<=", ">", "<"}
+
+// matchValue matches actual with the matcher, the matcher other than strings
+// is compared for equality. The string matchers are:
+//
+//	contains X, matches REGEXP, length [OP] N, OP X, or a literal
+//
+// And `exists` matches any value at the path.
+func matchValue(actual, matcher any) (bool, error) {
+	s, ok := matcher.(string)
+	if !ok {
+		return equalValues(actual, matcher), nil
+	}
+	s = strings.TrimSpace(s)
+
+	for _, op := range compareOperators {
+		if arg, ok := strings.CutPrefix(s, op); ok {
+			return compareValues(actual, op, parseOperand(arg))
+		}
+	}
+
+	word, arg, _ := strings.Cut(s, " ")
+	switch word {
+	case "contains":
+		return containsValue(actual, parseOperand(arg))
+
+	case "matches":
+		re, err := regexp.Compile(strings.TrimSpace(arg))
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(stringify(actual)), nil
+
+	case "length":
+		n, err := lengthOf(actual)
+		if err != nil {
+			return false, err
+		}
+		arg = strings.TrimSpace(arg)
+		for _, op := range compareOperators {
+			if rest, ok := strings.CutPrefix(arg, op); ok {
+				return compareValues(n, op, parseOperand(rest))
+			}
+		}
+		return compareValues(n, "==", parseOperand(arg))
+	}
+
+	return equalValues(actual, s), nil
+}
+
+// parseOperand returns a number, a bool or an unquoted string
+func parseOperand(s string) any {
+	s = strings.TrimSpace(s)
+	if isQuoted(s) {
+		return s[1 : len(s)-1]
+	}
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return n
+	}
+	if b, err := strconv.ParseBool(s); err == nil {
+		return b
+	}
+	return s
+}
+
+func compareValues(actual any, op string, expected any) (bool, error) {
+	a, aok := toFloat(actual)
+	b, bok := toFloat(expected)
+
+	if !aok || !bok {
+		switch op {
+		case "==":
+			return equalValues(actual, expected), nil
+		case "!=":
+			return !equalValues(actual, expected), nil
+		}
+		return false, fmt.Errorf("%s is not comparable with %s", formatValue(actual), formatValue(expected))
+	}
+
+	switch op {
+	case "==":
+		return a == b, nil
+	case "!=":
+		return a != b, nil
+	case ">=":
+		return a >= b, nil
+	case "<=":
+		return a <= b, nil
+	case ">":
+		return a > b, nil
+	case "<":
+		return a < b, nil
+	}
+
+	return false, fmt.Errorf("unknown operator %s", op)
+}
+
+// equalValues compares numbers by the value regardless of the type, and
+// a string with the formatted value of others.
+func equalValues(a, b any) bool {
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			return fa == fb
+		}
+	}
+
+	_, astr := a.(string)
+	_, bstr := b.(string)
+	if astr != bstr {
+		return stringify(a) == stringify(b)
+	}
+
+	return reflect.DeepEqual(normalizeJSON(a), normalizeJSON(b))
+}
+
+func containsValue(actual, x any) (bool, error) {
+	switch v := actual.(type) {
+	case string:
+		return strings.Contains(v, stringify(x)), nil
+	case []any:
+		for _, item := range v {
+			if equalValues(item, x) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]any, map[string]string:
+		_, ok := jsonChild(v, stringify(x))
+		return ok, nil
+	}
+	return false, fmt.Errorf("%s cannot contain values", formatValue(actual))
+}
+
+func lengthOf(v any) (int, error) {
+	if v != nil {
+		switch reflect.TypeOf(v).Kind() {
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+			return reflect.ValueOf(v).Len(), nil
+		}
+	}
+	return 0, fmt.Errorf("%s has no length", formatValue(v))
+}
+
+func toFloat(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// normalizeJSON returns maps and lists as decoded from JSON to compare them
+func normalizeJSON(v any) any {
+	if v == nil {
+		return nil
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return v
+		}
+		var res any
+		if err := json.Unmarshal(b, &res); err != nil {
+			return v
+		}
+		return res
+	}
+	return v
+}
+
+// formatValue quotes strings to show types of values in messages
+func formatValue(v any) string {
+	switch vv := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(vv)
+	}
+	return stringify(v)
+}
+
+// printExpects prints the results, with values of the failed ones
+func printExpects(w io.Writer, results []*ExpectResult, indent string) {
+	for _, r := range results {
+		if r.Status != StatusFailed {
+			fmt.Fprintf(w, "%s%s %s\n", indent, color.GreenString("✔︎"), r.Expect)
+			continue
+		}
+		fmt.Fprintf(w, "%s%s %s\n", indent, color.RedString("✘"), r.Expect)
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s  error: %s\n", indent, r.Error)
+			continue
+		}
+		if len(r.Diagnosis) == 1 && r.Diagnosis[0].Expr == r.Expect {
+			// the expectation itself is the failed condition
+			printOperands(w, r.Diagnosis[0].Operands, indent+"  ")
+			continue
+		}
+		if len(r.Diagnosis) > 0 {
+			printDiagnosis(w, r.Diagnosis, indent+"  ")
+			continue
+		}
+		fmt.Fprintf(w, "%s  expected: %s\n", indent, stringify(r.Expected))
+		fmt.Fprintf(w, "%s  actual:   %s\n", indent, formatValue(r.Actual))
+	}
+}
+
+func passedExpects(results []*ExpectResult) bool {
+	for _, r := range results {
+		if r.Status == StatusFailed {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *ExpectResult) message() string {
+	if r.Error != "" {
+		return fmt.Sprintf("expect %s: %s", r.Expect, r.Error)
+	}
+	if len(r.Diagnosis) > 0 {
+		return fmt.Sprintf("expect %s", diagnosisMessage(r.Diagnosis))
+	}
+	return fmt.Sprintf("expect %s: actual %s", r.Expect, formatValue(r.Actual))
+}
@@ -0,0 +1,101 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+)
+
+func TestExpectationsUnmarshalYAML(t *testing.T) {
+	src := `
+expect:
+- status: 200
+  headers.content-type: contains json
+- res.body.id > 0
+`
+	var got struct {
+		Expect Expectations `yaml:"expect"`
+	}
+	if err := yaml.Unmarshal([]byte(src), &got); err != nil {
+		t.Fatalf("yaml.Unmarshal error %s", err)
+	}
+
+	expects := Expectations{
+		{Path: "status", Matcher: uint64(200)},
+		{Path: "headers.content-type", Matcher: "contains json"},
+		{Expr: "res.body.id > 0"},
+	}
+	if !reflect.DeepEqual(got.Expect, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got.Expect)
+	}
+}
+
+func TestExpectationEval(t *testing.T) {
+	env := TestContext{
+		Res: map[string]any{
+			"code":    200,
+			"status":  "200 OK",
+			"headers": map[string]string{"Content-Type": "application/json; charset=utf-8"},
+			"body": map[string]any{
+				"id":    float64(42),
+				"name":  "probe",
+				"items": []any{"a", "b"},
+				"user":  map[string]any{"tags": []any{"admin"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		expect Expectation
+		status Status
+	}{
+		{Expectation{Path: "status", Matcher: uint64(200)}, StatusPassed},
+		{Expectation{Path: "status", Matcher: "200"}, StatusPassed},
+		{Expectation{Path: "status", Matcher: uint64(201)}, StatusFailed},
+		{Expectation{Path: "headers.content-type", Matcher: "contains json"}, StatusPassed},
+		{Expectation{Path: "headers.content-type", Matcher: "contains xml"}, StatusFailed},
+		{Expectation{Path: "body.items", Matcher: "length >= 1"}, StatusPassed},
+		{Expectation{Path: "body.items", Matcher: "length 3"}, StatusFailed},
+		{Expectation{Path: "body.items", Matcher: "contains b"}, StatusPassed},
+		{Expectation{Path: "body.items[0]", Matcher: "a"}, StatusPassed},
+		{Expectation{Path: "body.items", Matcher: []any{"a", "b"}}, StatusPassed},
+		{Expectation{Path: "body.id", Matcher: "> 40"}, StatusPassed},
+		{Expectation{Path: "body.id", Matcher: "!= 42"}, StatusFailed},
+		{Expectation{Path: "body.name", Matcher: "matches ^pro"}, StatusPassed},
+		{Expectation{Path: "body.name", Matcher: "> 1"}, StatusFailed},
+		{Expectation{Path: "body.user", Matcher: map[string]any{"tags": []any{"admin"}}}, StatusPassed},
+		{Expectation{Path: "body.user", Matcher: "exists"}, StatusPassed},
+		{Expectation{Path: "body.missing", Matcher: "exists"}, StatusFailed},
+		{Expectation{Expr: "res.body.id == 42"}, StatusPassed},
+		{Expectation{Expr: "res.body.name == 'x'"}, StatusFailed},
+		{Expectation{Expr: "res.body.name"}, StatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expect.String(), func(t *testing.T) {
+			got := tt.expect.Eval(env)
+			if got.Status != tt.status {
+				t.Errorf("\nExpected:\n%#v\nGot:\n%#v", tt.status, got)
+			}
+		})
+	}
+}
+
+func TestExpectationEval_Values(t *testing.T) {
+	env := TestContext{Res: map[string]any{"headers": map[string]string{"Content-Type": "text/html"}}}
+	got := Expectation{Path: "headers.content-type", Matcher: "contains json"}.Eval(env)
+
+	expects := &ExpectResult{
+		Expect:   "headers.content-type: contains json",
+		Status:   StatusFailed,
+		Expected: "contains json",
+		Actual:   "text/html",
+	}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+	if msg := got.message(); msg != `expect headers.content-type: contains json: actual "text/html"` {
+		t.Errorf("unexpected message: %s", msg)
+	}
+}
@@ -1,8 +1,11 @@
 package probe
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
+	"time"
 
 	ex "github.com/expr-lang/expr"
 )
@@ -10,6 +13,7 @@ import (
 const (
 	defaultExprStart = "{"
 	defaultExprEnd   = "}"
+	exprEscape       = `\`
 )
 
 type Expr struct {
@@ -24,47 +28,252 @@ func NewExpr() *Expr {
 	}
 }
 
-func (e *Expr) EvalTemplate(exprs map[string]any, env any) map[string]any {
-	results := make(map[string]any)
+// NewExprWithDelims returns Expr with the delimiters, e.g. `${{` and `}}`
+func NewExprWithDelims(start, end string) *Expr {
+	if start == "" || end == "" {
+		return NewExpr()
+	}
+	return &Expr{
+		start: start,
+		end:   end,
+	}
+}
+
+// EvalTemplate evaluates templates in values of maps and lists recursively
+func (e *Expr) EvalTemplate(exprs map[string]any, env any) (map[string]any, error) {
+	results := make(map[string]any, len(exprs))
+
+	// sorted for the stable error
+	for _, key := range sortedKeys(exprs) {
+		v, err := e.evalTemplateAny(exprs[key], env)
+		if err != nil {
+			return nil, newTemplateError(key, err)
+		}
+		results[key] = v
+	}
+
+	return results, nil
+}
 
-	for key, val := range exprs {
-		switch v := val.(type) {
-		case string:
-			output, err := e.EvalTemplateStr(v, env)
+func (e *Expr) evalTemplateAny(val any, env any) (any, error) {
+	switch v := val.(type) {
+	case string:
+		return e.EvalTemplateValue(v, env)
+
+	case map[string]any:
+		return e.EvalTemplate(v, env)
+
+	case []any:
+		results := make([]any, len(v))
+		for i, vv := range v {
+			out, err := e.evalTemplateAny(vv, env)
 			if err != nil {
-				results[key] = v
-				continue
+				return nil, newTemplateError(fmt.Sprintf("%d", i), err)
 			}
-			results[key] = output
+			results[i] = out
+		}
+		return results, nil
 
-		case map[string]any:
-			results[key] = e.EvalTemplate(v, env)
+	default:
+		return v, nil
+	}
+}
 
-		default:
-			results[key] = v
+// EvalTemplateStr evaluates all expressions in s and returns the string
+func (e *Expr) EvalTemplateStr(s string, env any) (string, error) {
+	v, err := e.EvalTemplateValue(s, env)
+	if err != nil {
+		return "", err
+	}
+	if str, ok := v.(string); ok {
+		return str, nil
+	}
+	return stringify(v), nil
+}
+
+// EvalTemplateValue evaluates all expressions in s.
+// When s consists only of one expression, the value keeps the type.
+func (e *Expr) EvalTemplateValue(s string, env any) (any, error) {
+	parts, err := e.parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parts) == 1 && parts[0].expr {
+		return evalTemplateExpr(parts[0].text, env)
+	}
+
+	var b strings.Builder
+	for _, p := range parts {
+		if !p.expr {
+			b.WriteString(p.text)
+			continue
+		}
+		out, err := evalTemplateExpr(p.text, env)
+		if err != nil {
+			return nil, err
 		}
+		b.WriteString(stringify(out))
 	}
 
-	return results
+	return b.String(), nil
 }
 
-func (e *Expr) EvalTemplateStr(s string, env any) (string, error) {
-	start := strings.Index(s, e.start)
-	end := strings.Index(s, e.end)
-	if start == -1 || end == -1 || start > end {
-		return s, nil
+func evalTemplateExpr(input string, env any) (any, error) {
+	out, err := EvalExpr(input, env)
+	if err != nil {
+		return nil, err
 	}
+	if out == nil {
+		return nil, fmt.Errorf("expression `%s` is unresolved", strings.TrimSpace(input))
+	}
+	return out, nil
+}
 
-	input := s[start+1 : end]
+type templatePart struct {
+	text string
+	expr bool
+}
 
-	output, err := EvalExpr(input, env)
-	if err != nil {
-		return "", err
+// parse splits s into literals and expressions.
+// The escaped delimiters are literals, and braces and quotes in expressions
+// are skipped when finding the end delimiter. The start delimiter of a JSON
+// object, such as `{"a": {b}}`, is a literal and the text in it is parsed,
+// so expressions nested in JSON are evaluated. Other text in the delimiters
+// which is not terminated or doesn't compile is an error.
+func (e *Expr) parse(s string) ([]templatePart, error) {
+	var parts []templatePart
+	var lit strings.Builder
+
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], exprEscape+e.start) {
+			lit.WriteString(e.start)
+			i += len(exprEscape) + len(e.start)
+			continue
+		}
+		if strings.HasPrefix(s[i:], exprEscape+e.end) {
+			lit.WriteString(e.end)
+			i += len(exprEscape) + len(e.end)
+			continue
+		}
+
+		if !strings.HasPrefix(s[i:], e.start) {
+			lit.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		begin := i + len(e.start)
+		if e.start == defaultExprStart && isJSONObject(s[begin:]) {
+			lit.WriteString(e.start)
+			i = begin
+			continue
+		}
+
+		end, ok := e.findEnd(s, begin)
+		if !ok {
+			return nil, fmt.Errorf("expression is not terminated with `%s`: %s", e.end, s[i:])
+		}
+		if _, err := ex.Compile(s[begin:end], exprFunctions...); err != nil {
+			return nil, fmt.Errorf("expression `%s` is invalid: %w", strings.TrimSpace(s[begin:end]), err)
+		}
+
+		if lit.Len() > 0 {
+			parts = append(parts, templatePart{text: lit.String()})
+			lit.Reset()
+		}
+		parts = append(parts, templatePart{text: s[begin:end], expr: true})
+		i = end + len(e.end)
+	}
+
+	if lit.Len() > 0 {
+		parts = append(parts, templatePart{text: lit.String()})
 	}
 
-	return fmt.Sprintf("%s%v%s", s[:start], output, s[end+1:]), nil
+	return parts, nil
+}
+
+// isJSONObject reports whether s after the start delimiter is the rest of a
+// JSON object, which is empty or begins with a quoted key and a colon. It is
+// never an expression, since expressions can't begin with a key.
+func isJSONObject(s string) bool {
+	s = strings.TrimLeft(s, " \t\r\n")
+	if strings.HasPrefix(s, "}") {
+		return true
+	}
+	if !strings.HasPrefix(s, `"`) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return strings.HasPrefix(strings.TrimLeft(s[i+1:], " \t\r\n"), ":")
+		}
+	}
+	return false
+}
+
+// findEnd returns the index of the end delimiter of the expression
+func (e *Expr) findEnd(s string, begin int) (int, bool) {
+	depth := 0
+	var quote byte
+
+	for i := begin; i < len(s); i++ {
+		c := s[i]
+
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		if depth == 0 && strings.HasPrefix(s[i:], e.end) {
+			return i, true
+		}
+
+		switch c {
+		case '"', '\'', '`':
+			quote = c
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+	}
+
+	return 0, false
+}
+
+// stringify formats maps and lists as JSON, floats without exponents and
+// the others as %v
+func stringify(v any) string {
+	if v == nil {
+		return ""
+	}
+
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+	}
+
+	return formatScalar(v)
 }
 
 func EvalExpr(input string, env any) (any, error) {
-	return ex.Eval(input, env)
+	program, err := ex.Compile(input, exprFunctions...)
+	if err != nil {
+		return nil, err
+	}
+	return ex.Run(program, env)
 }
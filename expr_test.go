@@ -0,0 +1,100 @@
+package probe
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEvalTemplateValue(t *testing.T) {
+	env := map[string]any{
+		"vars": map[string]any{"port": 8080, "host": "localhost", "ok": true, "list": []any{1, 2}},
+		"env":  map[string]string{"TOKEN": "abc"},
+	}
+
+	tests := []struct {
+		name    string
+		e       *Expr
+		input   string
+		expects any
+	}{
+		{name: "literal", e: NewExpr(), input: "hello", expects: "hello"},
+		{name: "multiple", e: NewExpr(), input: "http://{vars.host}:{vars.port}/", expects: "http://localhost:8080/"},
+		{name: "native int", e: NewExpr(), input: "{vars.port}", expects: 8080},
+		{name: "native bool", e: NewExpr(), input: "{vars.ok}", expects: true},
+		{name: "native list", e: NewExpr(), input: "{vars.list}", expects: []any{1, 2}},
+		{name: "list in string", e: NewExpr(), input: "ids={vars.list}", expects: "ids=[1,2]"},
+		{name: "braces in expression", e: NewExpr(), input: `{ {"a": "}"}.a }`, expects: "}"},
+		{name: "escape", e: NewExpr(), input: `\{"name": "{vars.host}"\}`, expects: `{"name": "localhost"}`},
+		{name: "json", e: NewExpr(), input: `{"a": 1}`, expects: `{"a": 1}`},
+		{name: "empty json", e: NewExpr(), input: `{}`, expects: `{}`},
+		{name: "nested json", e: NewExpr(), input: `{"a": {"b": [1, 2]}, "c": "{vars.host}"}`, expects: `{"a": {"b": [1, 2]}, "c": "localhost"}`},
+		{name: "expression in json", e: NewExpr(), input: `{"port": {vars.port}, "ok": {vars.ok}}`, expects: `{"port": 8080, "ok": true}`},
+		{name: "escaped invalid", e: NewExpr(), input: `\{vars.port +\}`, expects: `{vars.port +}`},
+		{name: "json with expression", e: NewExpr(), input: `[{"a": 1}, "{vars.host}"]`, expects: `[{"a": 1}, "localhost"]`},
+		{name: "escaped end", e: NewExpr(), input: `a\} {vars.port}`, expects: "a} 8080"},
+		{name: "delims", e: NewExprWithDelims("${{", "}}"), input: `{"token": "${{ env.TOKEN }}"}`, expects: `{"token": "abc"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.e.EvalTemplateValue(tt.input, env)
+			if err != nil {
+				t.Fatalf("EvalTemplateValue error %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.expects) {
+				t.Errorf("\nExpected:\n%#v\nGot:\n%#v", tt.expects, got)
+			}
+		})
+	}
+}
+
+func TestEvalTemplateValue_Errors(t *testing.T) {
+	env := map[string]any{"vars": map[string]any{}}
+
+	tests := []string{
+		"{vars.missing}",
+		"{unknown(1)}",
+		"{vars.port +}",
+		"not terminated {vars",
+		`{"a": {vars.port +}}`,
+	}
+
+	for _, input := range tests {
+		if got, err := NewExpr().EvalTemplateValue(input, env); err == nil {
+			t.Errorf("%s: expected error, but got %#v", input, got)
+		}
+	}
+}
+
+func TestEvalTemplate(t *testing.T) {
+	env := map[string]any{"vars": map[string]any{"id": 1}}
+	with := map[string]any{
+		"get":  "/users/{vars.id}",
+		"body": map[string]any{"id": "{vars.id}", "tags": []any{"{vars.id}", 2}},
+	}
+
+	got, err := NewExpr().EvalTemplate(with, env)
+	if err != nil {
+		t.Fatalf("EvalTemplate error %s", err)
+	}
+
+	expects := map[string]any{
+		"get":  "/users/1",
+		"body": map[string]any{"id": 1, "tags": []any{1, 2}},
+	}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+
+	_, err = NewExpr().EvalTemplate(map[string]any{"body": map[string]any{"ip": "{vars.ip}"}}, env)
+	var te *TemplateError
+	if !errors.As(err, &te) || te.Key != "body.ip" {
+		t.Errorf("expected TemplateError of body.ip, but got %#v", err)
+	}
+
+	_, err = NewExpr().EvalTemplate(map[string]any{"get": "/users/{vars.id +}"}, env)
+	if !errors.As(err, &te) || te.Key != "get" {
+		t.Errorf("expected TemplateError of get, but got %#v", err)
+	}
+}
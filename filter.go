@@ -0,0 +1,98 @@
+package probe
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Filter selects jobs and steps to run.
+// The tags of a step include the tags of its job.
+type Filter struct {
+	Tags     []string
+	SkipTags []string
+	Jobs     []string
+}
+
+// MatchTags reports whether the tags have any of Tags and none of SkipTags
+func (f Filter) MatchTags(tags []string) bool {
+	if f.SkipsTags(tags) {
+		return false
+	}
+
+	if len(f.Tags) == 0 {
+		return true
+	}
+
+	for _, t := range tags {
+		if slices.Contains(f.Tags, t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SkipsTags reports whether the tags have any of SkipTags
+func (f Filter) SkipsTags(tags []string) bool {
+	for _, t := range tags {
+		if slices.Contains(f.SkipTags, t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchJob reports whether the job name matches any of Jobs globs, in which
+// `*` matches any characters including `/`, `?` matches a character, `[...]`
+// matches a character of the class, and `\` escapes them.
+func (f Filter) MatchJob(name string) bool {
+	if len(f.Jobs) == 0 {
+		return true
+	}
+
+	for _, pattern := range f.Jobs {
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchGlob matches the whole name by the glob, and invalid globs match
+// nothing.
+func matchGlob(pattern, name string) bool {
+	var b strings.Builder
+	b.WriteString(`^(?s:`)
+	rs := []rune(pattern)
+	for i := 0; i < len(rs); i++ {
+		switch r := rs[i]; {
+		case r == '*':
+			b.WriteString(`.*`)
+		case r == '?':
+			b.WriteString(`.`)
+		case r == '\\' && i+1 < len(rs):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(rs[i])))
+		case r == '[':
+			end := strings.IndexRune(string(rs[i+1:]), ']')
+			if end < 0 {
+				return false
+			}
+			class := []rune(string(rs[i+1:])[:end])
+			i += len(class) + 1
+			b.WriteString("[" + string(class) + "]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString(`)$`)
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return false
+	}
+	return re.MatchString(name)
+}
@@ -0,0 +1,108 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterMatchTags(t *testing.T) {
+	f := Filter{Tags: []string{"smoke"}, SkipTags: []string{"slow"}}
+
+	tests := []struct {
+		tags    []string
+		expects bool
+	}{
+		{tags: []string{"smoke"}, expects: true},
+		{tags: []string{"smoke", "slow"}, expects: false},
+		{tags: []string{"nightly"}, expects: false},
+		{tags: nil, expects: false},
+	}
+
+	for _, tt := range tests {
+		if got := f.MatchTags(tt.tags); got != tt.expects {
+			t.Errorf("MatchTags(%#v): expected %t, but got %t", tt.tags, tt.expects, got)
+		}
+	}
+
+	if !(Filter{}).MatchTags(nil) {
+		t.Error("empty filter should match everything")
+	}
+}
+
+func TestFilterMatchJob(t *testing.T) {
+	f := Filter{Jobs: []string{"API *"}}
+
+	if !f.MatchJob("API users") {
+		t.Error("expected to match 'API users'")
+	}
+	if f.MatchJob("Mail") {
+		t.Error("expected not to match 'Mail'")
+	}
+
+	tests := []struct {
+		pattern string
+		name    string
+		expects bool
+	}{
+		{pattern: "API *", name: "API users/admin", expects: true},
+		{pattern: "v? *", name: "v2 API", expects: true},
+		{pattern: "[AB] job", name: "B job", expects: true},
+		{pattern: "[AB] job", name: "C job", expects: false},
+		{pattern: `Users \(\*\)`, name: "Users (*)", expects: true},
+		{pattern: `Users \(\*\)`, name: "Users (all)", expects: false},
+		{pattern: "Users.", name: "Users!", expects: false},
+		{pattern: "[AB job", name: "[AB job", expects: false},
+	}
+	for _, tt := range tests {
+		if got := (Filter{Jobs: []string{tt.pattern}}).MatchJob(tt.name); got != tt.expects {
+			t.Errorf("%q matching %q: expected %v, got %v", tt.pattern, tt.name, tt.expects, got)
+		}
+	}
+}
+
+func TestWorkflowApplyFilter(t *testing.T) {
+	w := &Workflow{
+		Jobs: []Job{
+			{ID: "login", Name: "Login", Steps: []Step{
+				{Uses: "http"},
+				{Uses: "http", Tags: []string{"slow"}},
+			}},
+			{Name: "Smoke", Needs: []string{"login"}, Tags: []string{"smoke"}, Steps: []Step{
+				{Uses: "http"},
+				{Uses: "http", Tags: []string{"slow"}},
+			}},
+			{Name: "Nightly", Steps: []Step{{Uses: "http"}}},
+		},
+	}
+
+	w.applyFilter(Filter{Tags: []string{"smoke"}, SkipTags: []string{"slow"}})
+
+	skipped := []bool{w.Jobs[0].skipped, w.Jobs[1].skipped, w.Jobs[2].skipped}
+	if expects := []bool{false, false, true}; !reflect.DeepEqual(skipped, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, skipped)
+	}
+	if expects := []bool{false, true}; !reflect.DeepEqual(w.Jobs[1].skipSteps, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, w.Jobs[1].skipSteps)
+	}
+	if expects := []bool{false, true}; !reflect.DeepEqual(w.Jobs[0].skipSteps, expects) {
+		t.Errorf("prerequisite should keep all steps except skip-tags: %#v", w.Jobs[0].skipSteps)
+	}
+}
+
+func TestWorkflowValidateNeeds(t *testing.T) {
+	w := &Workflow{
+		Jobs: []Job{
+			{ID: "a", Name: "A", Needs: []string{"b"}},
+			{ID: "b", Name: "B", Needs: []string{"a"}},
+			{ID: "c", Name: "D"},
+			{ID: "c", Name: "E"},
+			{Name: "C", Needs: []string{"unknown"}},
+		},
+	}
+
+	err := w.validateNeeds()
+	expects := "validation error:\njob id 'c' is duplicated\njob 'C' needs unknown job id 'unknown'\nneeds of job id 'a' have a cycle"
+	if err == nil || err.Error() != expects {
+		t.Errorf("\nExpected:\n%s\nGot:\n%v", expects, err)
+	}
+}
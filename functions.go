@@ -0,0 +1,235 @@
+package probe
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	ex "github.com/expr-lang/expr"
+)
+
+const randomChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// exprFunctions are the functions available in expressions,
+// in addition to the builtins of expr such as now(), fromJSON() and len().
+var exprFunctions = []ex.Option{
+	ex.Function("jsonpath", fnJSONPath, new(func(any, string) any)),
+	ex.Function("regexMatch", fnRegexMatch, new(func(string, string) bool)),
+	ex.Function("regexFind", fnRegexFind, new(func(string, string) string)),
+	ex.Function("base64Encode", fnBase64Encode, new(func(string) string)),
+	ex.Function("base64Decode", fnBase64Decode, new(func(string) string)),
+	ex.Function("sha256", fnSHA256, new(func(string) string)),
+	ex.Function("hmac", fnHMAC, new(func(string, string) string), new(func(string, string, string) string)),
+	ex.Function("uuid", fnUUID, new(func() string)),
+	ex.Function("dateAdd", fnDateAdd, new(func(any, string) time.Time)),
+	ex.Function("parseTime", fnParseTime, new(func(string) time.Time), new(func(string, string) time.Time)),
+	ex.Function("random", fnRandom, new(func(int) int)),
+	ex.Function("randomString", fnRandomString, new(func(int) string)),
+	ex.Function("urlEncode", fnURLEncode, new(func(string) string)),
+	ex.Function("compactJSON", fnCompactJSON, new(func(any) string)),
+}
+
+// jsonpath(obj, "$.a[*].b")
+func fnJSONPath(params ...any) (any, error) {
+	return JSONPath(params[0], str(params[1]))
+}
+
+// regexMatch(pattern, s)
+func fnRegexMatch(params ...any) (any, error) {
+	re, err := regexp.Compile(str(params[0]))
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString(str(params[1])), nil
+}
+
+// regexFind(pattern, s) returns the first group if the pattern has groups,
+// otherwise the whole match.
+func fnRegexFind(params ...any) (any, error) {
+	re, err := regexp.Compile(str(params[0]))
+	if err != nil {
+		return nil, err
+	}
+	m := re.FindStringSubmatch(str(params[1]))
+	switch {
+	case m == nil:
+		return "", nil
+	case len(m) > 1:
+		return m[1], nil
+	default:
+		return m[0], nil
+	}
+}
+
+func fnBase64Encode(params ...any) (any, error) {
+	return base64.StdEncoding.EncodeToString([]byte(str(params[0]))), nil
+}
+
+func fnBase64Decode(params ...any) (any, error) {
+	b, err := base64.StdEncoding.DecodeString(str(params[0]))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// sha256(s) returns the hex digest
+func fnSHA256(params ...any) (any, error) {
+	sum := sha256.Sum256([]byte(str(params[0])))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// hmac(key, message[, algorithm]) returns the hex digest, the algorithm is
+// sha1, sha256 (default) or sha512.
+func fnHMAC(params ...any) (any, error) {
+	alg := "sha256"
+	if len(params) > 2 {
+		alg = str(params[2])
+	}
+
+	var h func() hash.Hash
+	switch alg {
+	case "sha1":
+		h = sha1.New
+	case "sha256":
+		h = sha256.New
+	case "sha512":
+		h = sha512.New
+	default:
+		return nil, fmt.Errorf("hmac: unsupported algorithm %s", alg)
+	}
+
+	mac := hmac.New(h, []byte(str(params[0])))
+	mac.Write([]byte(str(params[1])))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// uuid() returns a random UUID of version 4
+func fnUUID(params ...any) (any, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// dateAdd(t, "1h30m") accepts time or RFC3339 string
+func fnDateAdd(params ...any) (any, error) {
+	var t time.Time
+	switch v := params[0].(type) {
+	case time.Time:
+		t = v
+	case string:
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, err
+		}
+		t = parsed
+	default:
+		return nil, fmt.Errorf("dateAdd: unsupported time %T", v)
+	}
+
+	d, err := time.ParseDuration(str(params[1]))
+	if err != nil {
+		return nil, err
+	}
+
+	return t.Add(d), nil
+}
+
+// parseTime(s[, layout]), the layout is RFC3339 by default
+func fnParseTime(params ...any) (any, error) {
+	layout := time.RFC3339
+	if len(params) > 1 {
+		layout = str(params[1])
+	}
+	return time.Parse(layout, str(params[0]))
+}
+
+// random(n) returns an int in [0, n)
+func fnRandom(params ...any) (any, error) {
+	max, err := toInt(params[0])
+	if err != nil {
+		return nil, err
+	}
+	if max <= 0 {
+		return nil, fmt.Errorf("random: n must be positive: %d", max)
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return nil, err
+	}
+	return int(n.Int64()), nil
+}
+
+// randomString(n) returns alphanumeric characters of n
+func fnRandomString(params ...any) (any, error) {
+	n, err := toInt(params[0])
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("randomString: n must not be negative: %d", n)
+	}
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(randomChars)))
+	for i := range b {
+		k, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return nil, err
+		}
+		b[i] = randomChars[k.Int64()]
+	}
+	return string(b), nil
+}
+
+func fnURLEncode(params ...any) (any, error) {
+	return url.QueryEscape(str(params[0])), nil
+}
+
+// compactJSON(v) returns compact JSON, while the builtin toJSON indents it
+func fnCompactJSON(params ...any) (any, error) {
+	b, err := json.Marshal(params[0])
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// str returns s as is, or formats the other values as templates do
+func str(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return stringify(v)
+}
+
+func toInt(v any) (int, error) {
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case int64:
+		return int(n), nil
+	case uint64:
+		return int(n), nil
+	case float64:
+		return int(n), nil
+	case string:
+		return strconv.Atoi(n)
+	}
+	return 0, fmt.Errorf("%v is not a number", v)
+}
@@ -0,0 +1,89 @@
+package probe
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestExprFunctions(t *testing.T) {
+	env := map[string]any{
+		"res": map[string]any{
+			"body": map[string]any{
+				"items": []any{
+					map[string]any{"id": 1, "name": "a"},
+					map[string]any{"id": 2, "name": "b"},
+				},
+			},
+			"headers": map[string]string{"Location": "/users/42"},
+		},
+		"vars": map[string]any{"n": uint64(3)},
+	}
+
+	tests := []struct {
+		input   string
+		expects any
+	}{
+		{input: `jsonpath(res.body, "$.items[*].id")`, expects: []any{1, 2}},
+		{input: `jsonpath(res.body, "$.items[-1].name")`, expects: "b"},
+		{input: `jsonpath(res.body, "$..name")`, expects: []any{"a", "b"}},
+		{input: `jsonpath(res.body, "$.missing")`, expects: nil},
+		{input: `regexMatch("^/users/\\d+$", res.headers.Location)`, expects: true},
+		{input: `regexFind("/users/(\\d+)", res.headers.Location)`, expects: "42"},
+		{input: `regexFind("\\d+", res.headers.Location)`, expects: "42"},
+		{input: `base64Encode("user:pass")`, expects: "dXNlcjpwYXNz"},
+		{input: `base64Decode("dXNlcjpwYXNz")`, expects: "user:pass"},
+		{input: `sha256("abc")`, expects: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{input: `hmac("key", "The quick brown fox jumps over the lazy dog")`, expects: "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"},
+		{input: `hmac("key", "The quick brown fox jumps over the lazy dog", "sha1")`, expects: "de7c9b85b8b78aa6bc8a7a36f70a90701c9db4d9"},
+		{input: `urlEncode("a b&c")`, expects: "a+b%26c"},
+		{input: `compactJSON({"a": [1, 2]})`, expects: `{"a":[1,2]}`},
+		{input: `toJSON({"a": 1})`, expects: "{\n  \"a\": 1\n}"},
+		{input: `jsonpath({"a]b": 1, "c": {"d'e": 2}}, "$['a]b']")`, expects: 1},
+		{input: `jsonpath({"a]b": 1, "c": {"d'e": 2}}, "$.c[\"d'e\"]")`, expects: 2},
+		{input: `dateAdd("2024-01-01T00:00:00Z", "36h").Format("2006-01-02")`, expects: "2024-01-02"},
+		{input: `parseTime("2024/01/02", "2006/01/02").Year()`, expects: 2024},
+		{input: `len(randomString(vars.n))`, expects: 3},
+		{input: `random(1)`, expects: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := EvalExpr(tt.input, env)
+			if err != nil {
+				t.Fatalf("EvalExpr error %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.expects) {
+				t.Errorf("\nExpected:\n%#v\nGot:\n%#v", tt.expects, got)
+			}
+		})
+	}
+}
+
+func TestExprFunctions_UUID(t *testing.T) {
+	got, err := EvalExpr(`uuid()`, nil)
+	if err != nil {
+		t.Fatalf("EvalExpr error %s", err)
+	}
+	re := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if s, ok := got.(string); !ok || !re.MatchString(s) {
+		t.Errorf("uuid() is not a v4 UUID: %#v", got)
+	}
+}
+
+func TestExprFunctions_Errors(t *testing.T) {
+	tests := []string{
+		`base64Decode("!")`,
+		`hmac("key", "msg", "md5")`,
+		`random(0)`,
+		`jsonpath({}, "$[")`,
+		`jsonpath({}, "$['a]")`,
+		`regexMatch("(", "a")`,
+	}
+
+	for _, input := range tests {
+		if got, err := EvalExpr(input, nil); err == nil {
+			t.Errorf("%s: expected error, but got %#v", input, got)
+		}
+	}
+}
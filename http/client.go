@@ -2,6 +2,7 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -18,19 +19,19 @@ type TransportOptions struct {
 }
 
 type Req struct {
-	URL    string            `map:"url" validate"required"`
-	Method string            `map:"method" validate:"required"`
-	Proto  string            `map:"ver"`
-	Header map[string]string `map:"headers"`
-	Body   []byte            `map:"body"`
+	URL    string            `map:"url" validate:"required" description:"URL of the request"`
+	Method string            `map:"method" validate:"required" default:"GET" description:"HTTP method"`
+	Proto  string            `map:"ver" default:"HTTP/1.1" description:"HTTP version"`
+	Header map[string]string `map:"headers" description:"Request headers"`
+	Body   []byte            `map:"body" description:"Request body, maps are encoded by the content-type"`
 	cb     *Callback
 }
 
 type Res struct {
-	Status string            `map:"status"`
-	Code   int               `map:"code"`
-	Header map[string]string `map:"headers"`
-	Body   []byte            `map:"body"`
+	Status string            `map:"status" description:"Status line such as 200 OK"`
+	Code   int               `map:"code" description:"Status code"`
+	Header map[string]string `map:"headers" description:"Response headers"`
+	Body   []byte            `map:"body" description:"Response body"`
 }
 
 type Result struct {
@@ -54,7 +55,12 @@ func (r *Req) Do() (*Result, error) {
 		return nil, errors.New("Req.URL is required")
 	}
 
-	req, err := hp.NewRequest(r.Method, r.URL, bytes.NewBuffer(r.Body))
+	ctx := context.Background()
+	if r.cb != nil && r.cb.ctx != nil {
+		ctx = r.cb.ctx
+	}
+
+	req, err := hp.NewRequestWithContext(ctx, r.Method, r.URL, bytes.NewBuffer(r.Body))
 	if err != nil {
 		return nil, err
 	}
@@ -107,6 +113,7 @@ func (r *Req) Do() (*Result, error) {
 type Option func(*Callback)
 
 type Callback struct {
+	ctx    context.Context
 	before func(req *hp.Request)
 	after  func(res *hp.Response)
 }
@@ -141,7 +148,17 @@ func HeaderToStringValue(data map[string]any) map[string]any {
 }
 
 func Request(data map[string]string, opts ...Option) (map[string]string, error) {
-	m := HeaderToStringValue(probe.UnflattenInterface(data))
+	ret, err := Send(probe.UnflattenInterface(data), opts...)
+	if err != nil {
+		return map[string]string{}, err
+	}
+
+	return probe.FlattenInterface(ret), nil
+}
+
+// Send sends the request of the typed data, and returns the typed result.
+func Send(data map[string]any, opts ...Option) (map[string]any, error) {
+	m := HeaderToStringValue(data)
 	r := NewReq()
 
 	cb := &Callback{}
@@ -151,20 +168,22 @@ func Request(data map[string]string, opts ...Option) (map[string]string, error)
 	r.cb = cb
 
 	if err := probe.MapToStructByTags(m, r); err != nil {
-		return map[string]string{}, err
+		return map[string]any{}, probe.NewActionError(probe.ErrorValidation, err)
 	}
 
 	ret, err := r.Do()
 	if err != nil {
-		return map[string]string{}, err
+		return map[string]any{}, err
 	}
 
-	mapRet, err := probe.StructToMapByTags(ret)
-	if err != nil {
-		return map[string]string{}, err
-	}
+	return probe.StructToMapByTags(ret)
+}
 
-	return probe.FlattenInterface(mapRet), nil
+// WithContext cancels the request with ctx
+func WithContext(ctx context.Context) Option {
+	return func(c *Callback) {
+		c.ctx = ctx
+	}
 }
 
 func WithBefore(f func(req *hp.Request)) Option {
@@ -1,10 +1,13 @@
 package http
 
 import (
+	"errors"
+	hp "net/http"
 	"reflect"
 	"testing"
 
 	"github.com/jarcoal/httpmock"
+	"github.com/linyows/probe"
 )
 
 func TestNewReq(t *testing.T) {
@@ -46,3 +49,36 @@ func TestDo(t *testing.T) {
 		t.Errorf("\nExpected:\n%s\nGot:\n%s", expects, got.Res.Body)
 	}
 }
+
+func TestSend_Scalars(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var count string
+	httpmock.RegisterResponder("GET", "http://localhost:8080/foo", func(req *hp.Request) (*hp.Response, error) {
+		count = req.Header.Get("X-Count")
+		return httpmock.NewStringResponse(200, "ok"), nil
+	})
+
+	got, err := Send(map[string]any{
+		"url":     "http://localhost:8080/foo",
+		"method":  "GET",
+		"ver":     2,
+		"headers": map[string]any{"x-count": float64(3)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != "3" {
+		t.Errorf("expected the header of the number, got %q", count)
+	}
+	if req := got["req"].(map[string]any); req["ver"] != "2" {
+		t.Errorf("expected the version as a string, got %#v", req["ver"])
+	}
+
+	_, err = Send(map[string]any{"url": map[string]any{"host": "localhost"}})
+	var ae *probe.ActionError
+	if !errors.As(err, &ae) || ae.Category != probe.ErrorValidation {
+		t.Errorf("expected the validation error, got %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+package probe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type jsonPathKind int
+
+const (
+	jsonPathKey jsonPathKind = iota
+	jsonPathIndex
+	jsonPathWildcard
+	jsonPathDescendant
+)
+
+type jsonPathStep struct {
+	kind  jsonPathKind
+	key   string
+	index int
+}
+
+// JSONPath returns the values at the path of obj.
+// It supports a subset of JSONPath: `$`, `.key`, `['key']`, `[n]`, `[*]`,
+// `.*` and `..key`. A path without wildcards returns the value itself,
+// and the others return a list of matched values.
+func JSONPath(obj any, path string) (any, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := selectJSONPath([]any{obj}, steps)
+	if isDefiniteJSONPath(steps) {
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return values[0], nil
+	}
+
+	if values == nil {
+		values = []any{}
+	}
+	return values, nil
+}
+
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	p := strings.TrimSpace(path)
+	p = strings.TrimPrefix(p, "$")
+
+	var steps []jsonPathStep
+	for len(p) > 0 {
+		switch {
+		case strings.HasPrefix(p, ".."):
+			key, rest := cutJSONPathKey(p[2:])
+			if key == "" {
+				return nil, fmt.Errorf("jsonpath %s: key is required after ..", path)
+			}
+			steps = append(steps, jsonPathStep{kind: jsonPathDescendant, key: key})
+			p = rest
+
+		case strings.HasPrefix(p, "."):
+			key, rest := cutJSONPathKey(p[1:])
+			switch key {
+			case "":
+				return nil, fmt.Errorf("jsonpath %s: key is required after .", path)
+			case "*":
+				steps = append(steps, jsonPathStep{kind: jsonPathWildcard})
+			default:
+				steps = append(steps, jsonPathStep{kind: jsonPathKey, key: key})
+			}
+			p = rest
+
+		case strings.HasPrefix(p, "["):
+			end := subscriptEnd(p)
+			if end == -1 {
+				return nil, fmt.Errorf("jsonpath %s: ] is missing", path)
+			}
+			inner := strings.TrimSpace(p[1:end])
+			p = p[end+1:]
+
+			if inner == "*" {
+				steps = append(steps, jsonPathStep{kind: jsonPathWildcard})
+			} else if isQuoted(inner) {
+				steps = append(steps, jsonPathStep{kind: jsonPathKey, key: inner[1 : len(inner)-1]})
+			} else if n, err := strconv.Atoi(inner); err == nil {
+				steps = append(steps, jsonPathStep{kind: jsonPathIndex, index: n})
+			} else {
+				return nil, fmt.Errorf("jsonpath %s: invalid subscript [%s]", path, inner)
+			}
+
+		default:
+			// allow the first key without a dot, e.g. `a.b`
+			if len(steps) > 0 {
+				return nil, fmt.Errorf("jsonpath %s: unexpected %s", path, p)
+			}
+			p = "." + p
+		}
+	}
+
+	return steps, nil
+}
+
+// subscriptEnd returns the index of `]` closing the subscript at the start of
+// p, which is after the closing quote of quoted keys such as ['a]b'].
+func subscriptEnd(p string) int {
+	inner := strings.TrimLeft(p[1:], " ")
+	if inner == "" || (inner[0] != '\'' && inner[0] != '"') {
+		return strings.Index(p, "]")
+	}
+
+	open := len(p) - len(inner)
+	closing := strings.IndexByte(p[open+1:], inner[0])
+	if closing == -1 {
+		return -1
+	}
+	rest := open + 1 + closing + 1
+	end := strings.Index(p[rest:], "]")
+	if end == -1 {
+		return -1
+	}
+	return rest + end
+}
+
+func cutJSONPathKey(p string) (string, string) {
+	i := strings.IndexAny(p, ".[")
+	if i == -1 {
+		return p, ""
+	}
+	return p[:i], p[i:]
+}
+
+func isDefiniteJSONPath(steps []jsonPathStep) bool {
+	for _, s := range steps {
+		if s.kind == jsonPathWildcard || s.kind == jsonPathDescendant {
+			return false
+		}
+	}
+	return true
+}
+
+func selectJSONPath(values []any, steps []jsonPathStep) []any {
+	for _, step := range steps {
+		var next []any
+		for _, v := range values {
+			next = append(next, selectJSONPathStep(v, step)...)
+		}
+		values = next
+	}
+	return values
+}
+
+func selectJSONPathStep(v any, step jsonPathStep) []any {
+	switch step.kind {
+	case jsonPathKey:
+		if child, ok := jsonChild(v, step.key); ok {
+			return []any{child}
+		}
+
+	case jsonPathIndex:
+		if list, ok := v.([]any); ok {
+			i := step.index
+			if i < 0 {
+				i += len(list)
+			}
+			if i >= 0 && i < len(list) {
+				return []any{list[i]}
+			}
+		}
+
+	case jsonPathWildcard:
+		return jsonChildren(v)
+
+	case jsonPathDescendant:
+		var res []any
+		if child, ok := jsonChild(v, step.key); ok {
+			res = append(res, child)
+		}
+		for _, child := range jsonChildren(v) {
+			res = append(res, selectJSONPathStep(child, step)...)
+		}
+		return res
+	}
+
+	return nil
+}
+
+func jsonChild(v any, key string) (any, bool) {
+	switch vv := v.(type) {
+	case map[string]any:
+		child, ok := vv[key]
+		return child, ok
+	case map[string]string:
+		child, ok := vv[key]
+		return child, ok
+	}
+	return nil, false
+}
+
+func jsonChildren(v any) []any {
+	switch vv := v.(type) {
+	case map[string]any:
+		res := make([]any, 0, len(vv))
+		for _, k := range sortedKeys(vv) {
+			res = append(res, vv[k])
+		}
+		return res
+	case []any:
+		return vv
+	}
+	return nil
+}
+
+func isQuoted(s string) bool {
+	if len(s) < 2 {
+		return false
+	}
+	q := s[0]
+	return (q == '\'' || q == '"') && s[len(s)-1] == q
+}
+
+// replaceJSONPath replaces the values at the path of obj with v in place
+func replaceJSONPath(obj any, path string, v any) error {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		return fmt.Errorf("jsonpath %s: the root cannot be replaced", path)
+	}
+
+	last := steps[len(steps)-1]
+	for _, parent := range selectJSONPath([]any{obj}, steps[:len(steps)-1]) {
+		replaceJSONChild(parent, last, v)
+	}
+
+	return nil
+}
+
+func replaceJSONChild(parent any, step jsonPathStep, v any) {
+	switch step.kind {
+	case jsonPathKey:
+		if m, ok := parent.(map[string]any); ok {
+			if _, exists := m[step.key]; exists {
+				m[step.key] = v
+			}
+		}
+
+	case jsonPathIndex:
+		if list, ok := parent.([]any); ok {
+			i := step.index
+			if i < 0 {
+				i += len(list)
+			}
+			if i >= 0 && i < len(list) {
+				list[i] = v
+			}
+		}
+
+	case jsonPathWildcard:
+		switch vv := parent.(type) {
+		case map[string]any:
+			for k := range vv {
+				vv[k] = v
+			}
+		case []any:
+			for i := range vv {
+				vv[i] = v
+			}
+		}
+
+	case jsonPathDescendant:
+		replaceJSONChild(parent, jsonPathStep{kind: jsonPathKey, key: step.key}, v)
+		for _, child := range jsonChildren(parent) {
+			replaceJSONChild(child, step, v)
+		}
+	}
+}
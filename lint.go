@@ -0,0 +1,94 @@
+package probe
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+)
+
+// LintIssue is a problem of a step found by Lint, Path is the position in
+// the workflow such as `jobs[0].steps[1].with.url`.
+type LintIssue struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.Path, i.Message)
+}
+
+// Lint checks the `with` of steps by the manifests of the actions: unknown
+// params and missing required params. manifest returns nil for actions
+// without manifests, whose steps are not checked as well as the actions
+// without params.
+func (w *Workflow) Lint(manifest func(name string) (*Manifest, error)) []LintIssue {
+	type cached struct {
+		m   *Manifest
+		err error
+	}
+	manifests := map[string]cached{}
+
+	var issues []LintIssue
+	for i, job := range w.Jobs {
+		for j, st := range job.Steps {
+			path := fmt.Sprintf("jobs[%d].steps[%d]", i, j)
+
+			c, ok := manifests[st.Uses]
+			if !ok {
+				c.m, c.err = manifest(st.Uses)
+				manifests[st.Uses] = c
+			}
+			if c.err != nil {
+				issues = append(issues, LintIssue{Path: path + ".uses", Message: c.err.Error()})
+				continue
+			}
+			if c.m == nil || !c.m.HasParams() {
+				continue
+			}
+
+			issues = append(issues, lintWith(path+".with", st.Uses, st.With, c.m)...)
+		}
+	}
+
+	return issues
+}
+
+func lintWith(path, uses string, with map[string]any, m *Manifest) []LintIssue {
+	var issues []LintIssue
+
+	keys := make([]string, 0, len(with))
+	for k := range with {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, ok := m.Param(k); !ok {
+			issues = append(issues, LintIssue{Path: path + "." + k, Message: fmt.Sprintf("unknown param of %s", uses)})
+		}
+	}
+
+	for _, p := range m.Params {
+		if _, ok := with[p.Name]; p.Required && !ok {
+			issues = append(issues, LintIssue{Path: path + "." + p.Name, Message: fmt.Sprintf("required param of %s is missing", uses)})
+		}
+	}
+
+	return issues
+}
+
+// Lint loads the workflow and checks the steps by the manifests of the
+// actions, which are started with the checksums of the workflow.
+func (p *Probe) Lint() ([]LintIssue, error) {
+	if err := p.Load(); err != nil {
+		return nil, err
+	}
+
+	pool := NewActionPool(io.Discard, false)
+	defer pool.Close()
+	pool.specs = p.workflow.Actions
+	pool.dir = filepath.Dir(p.workflow.path)
+	pool.registry = p.config.Registry
+
+	return p.workflow.Lint(pool.Manifest), nil
+}
@@ -0,0 +1,52 @@
+package probe
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWorkflow_Lint(t *testing.T) {
+	manifests := map[string]*Manifest{
+		"http": {Name: "http", Params: []Param{
+			{Name: "url", Type: "string", Required: true},
+			{Name: "get", Type: "string"},
+		}},
+		"hello": {Name: "hello"},
+		"v1":    nil,
+	}
+	calls := 0
+	manifest := func(name string) (*Manifest, error) {
+		calls++
+		m, ok := manifests[name]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return m, nil
+	}
+
+	w := Workflow{Jobs: []Job{
+		{Steps: []Step{
+			{Uses: "http", With: map[string]any{"url": "http://localhost", "get": "/"}},
+			{Uses: "http", With: map[string]any{"get": "/", "heders": map[string]any{}}},
+		}},
+		{Steps: []Step{
+			{Uses: "hello", With: map[string]any{"anything": 1}},
+			{Uses: "v1", With: map[string]any{"anything": 1}},
+			{Uses: "nope"},
+		}},
+	}}
+
+	got := w.Lint(manifest)
+	expects := []LintIssue{
+		{Path: "jobs[0].steps[1].with.heders", Message: "unknown param of http"},
+		{Path: "jobs[0].steps[1].with.url", Message: "required param of http is missing"},
+		{Path: "jobs[1].steps[2].uses", Message: "not found"},
+	}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+	if calls != 4 {
+		t.Errorf("expected the manifests to be fetched once per action, got %d calls", calls)
+	}
+}
@@ -1,6 +1,7 @@
 package mail
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
@@ -18,33 +19,63 @@ func NewBulk(p probe.ActionsParams) (*Bulk, error) {
 }
 
 type Bulk struct {
-	Addr       string `map:"addr" validate:"required"`
-	From       string `map:"from" validate:"required"`
-	To         string `map:"to" validate:"required"`
-	Subject    string `map:"subject"`
-	MyHostname string `map:"myhostname"`
-	Session    int    `map:"session"`
-	Message    int    `map:"message"`
-	Length     int    `map:"length"`
+	Addr       string `map:"addr" validate:"required" description:"Address of the SMTP server as host:port"`
+	From       string `map:"from" validate:"required" description:"Envelope sender"`
+	To         string `map:"to" validate:"required" description:"Envelope recipients separated by commas"`
+	Subject    string `map:"subject" description:"Subject, which is suffixed by an ID per message"`
+	MyHostname string `map:"myhostname" description:"Hostname of the client"`
+	Session    int    `map:"session" description:"Number of concurrent sessions"`
+	Message    int    `map:"message" description:"Number of messages in total"`
+	Length     int    `map:"length" description:"Length of the filler text of the body"`
+
+	// OnSent is called with the number of messages and bytes sent so far
+	// when a message is sent by any session
+	OnSent func(sent, bytes int)
 
 	mu    sync.Mutex
 	count int
+	sent  int
+	bytes int
 }
 
-func (b *Bulk) Deliver() {
+// Deliver sends the messages by the sessions concurrently, and returns the
+// first error of the sessions.
+func (b *Bulk) Deliver() error {
+	return b.DeliverContext(context.Background())
+}
+
+// DeliverContext is Deliver stopping the sessions when ctx is done
+func (b *Bulk) DeliverContext(ctx context.Context) error {
 	var wg sync.WaitGroup
+	errs := make(chan error, b.Session)
 
 	for i := 0; i < b.Session; i++ {
 		wg.Add(1)
-		go b.Send(&wg)
+		go func() {
+			defer wg.Done()
+			errs <- b.send(ctx)
+		}()
 	}
 
 	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (b *Bulk) Send(wg *sync.WaitGroup) error {
 	defer wg.Done()
 
+	return b.send(context.Background())
+}
+
+func (b *Bulk) send(ctx context.Context) error {
 	n := b.calcMessageNumEachSession()
 	if n == 0 {
 		return nil
@@ -57,9 +88,22 @@ func (b *Bulk) Send(wg *sync.WaitGroup) error {
 		Data:             b.makeData(),
 		StartTLSDisabled: true,
 		MessageCount:     n,
+		OnSent:           b.sentMessage,
 	}
 
-	return m.Send()
+	return m.SendContext(ctx)
+}
+
+func (b *Bulk) sentMessage(size int) {
+	b.mu.Lock()
+	b.sent++
+	b.bytes += size
+	sent, bytes := b.sent, b.bytes
+	b.mu.Unlock()
+
+	if b.OnSent != nil {
+		b.OnSent(sent, bytes)
+	}
 }
 
 func (b *Bulk) calcMessageNumEachSession() int {
@@ -0,0 +1,82 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/linyows/probe"
+)
+
+// closedAddr returns the address nothing listens on
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func TestBulkDeliver_Error(t *testing.T) {
+	b := &Bulk{
+		Addr:    closedAddr(t),
+		From:    "alice@example.com",
+		To:      "bob@example.com",
+		Session: 2,
+		Message: 4,
+	}
+
+	err := b.Deliver()
+	if err == nil {
+		t.Fatal("expected the error of the sessions")
+	}
+	if ae := probe.ClassifyError(err); ae.Category != probe.ErrorConnection {
+		t.Errorf("expected the connection error, got %s", ae)
+	}
+}
+
+func TestBulkDeliverContext_Cancel(t *testing.T) {
+	// the server accepts sessions but never greets
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	b := &Bulk{
+		Addr:    l.Addr().String(),
+		From:    "alice@example.com",
+		To:      "bob@example.com",
+		Session: 2,
+		Message: 4,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- b.DeliverContext(ctx) }()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the error of the deadline, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the sessions stopped by the context")
+	}
+}
@@ -2,8 +2,10 @@ package mail
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"errors"
+	"net"
 	"net/smtp"
 )
 
@@ -15,9 +17,17 @@ type Mail struct {
 	Auth             smtp.Auth
 	StartTLSDisabled bool
 	MessageCount     int
+	// OnSent is called with the size of the data when a message is sent
+	OnSent func(size int)
 }
 
 func (m *Mail) Send() error {
+	return m.SendContext(context.Background())
+}
+
+// SendContext sends the messages until ctx is done. The session is stopped
+// between messages, and interrupted by closing the connection in progress.
+func (m *Mail) SendContext(ctx context.Context) (err error) {
 	if err := validateLine(m.MailFrom); err != nil {
 		return err
 	}
@@ -26,7 +36,22 @@ func (m *Mail) Send() error {
 			return err
 		}
 	}
-	c, err := Dial(m.Addr)
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", m.Addr)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer func() {
+		// errors of the connection closed by ctx are the error of ctx
+		if !stop() && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+	}()
+
+	host, _, _ := net.SplitHostPort(m.Addr)
+	c, err := NewClient(conn, host)
 	if err != nil {
 		return err
 	}
@@ -55,6 +80,9 @@ func (m *Mail) Send() error {
 	}
 
 	for i := 0; i < m.MessageCount; i++ {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 		if err = c.Mail(m.MailFrom); err != nil {
 			return err
 		}
@@ -68,7 +96,7 @@ func (m *Mail) Send() error {
 			return err
 		}
 
-		_, err = w.Write(m.appendIDtoSubject(m.Data))
+		n, err := w.Write(m.appendIDtoSubject(m.Data))
 		if err != nil {
 			return err
 		}
@@ -76,6 +104,9 @@ func (m *Mail) Send() error {
 		if err != nil {
 			return err
 		}
+		if m.OnSent != nil {
+			m.OnSent(n)
+		}
 	}
 
 	return c.Quit()
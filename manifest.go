@@ -0,0 +1,257 @@
+package probe
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/linyows/probe/pb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	tagDefault     = "default"
+	tagDescription = "description"
+)
+
+// Manifest describes an action, the params of `with` and the output fields
+type Manifest struct {
+	Name        string  `json:"name"`
+	Version     string  `json:"version,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Params      []Param `json:"params,omitempty"`
+	Outputs     []Field `json:"outputs,omitempty"`
+}
+
+// Param is a key of `with`. Type is one of string, integer, number,
+// boolean, array, object and any.
+type Param struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required,omitempty"`
+	Default     any    `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Field is an output field of the action, nested fields are joined by dots
+type Field struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+// ManifestActions are actions describing themselves
+type ManifestActions interface {
+	Manifest() Manifest
+}
+
+// HasParams reports whether the params are declared, and actions without
+// them such as ones taking maps accept any keys.
+func (m *Manifest) HasParams() bool {
+	return len(m.Params) > 0
+}
+
+// Param returns the param of the name
+func (m *Manifest) Param(name string) (Param, bool) {
+	for _, p := range m.Params {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Param{}, false
+}
+
+// WithSchema returns the JSON Schema of `with` for the workflow schema.
+// Since values may be filled by templates, non-string scalars also accept
+// strings, and nothing is required.
+func (m *Manifest) WithSchema() map[string]any {
+	if !m.HasParams() {
+		return map[string]any{"type": "object"}
+	}
+
+	props := map[string]any{}
+	for _, p := range m.Params {
+		s := map[string]any{}
+		switch p.Type {
+		case "", "any":
+		case "string", "object":
+			s["type"] = p.Type
+		default:
+			s["type"] = []string{p.Type, "string"}
+		}
+		if p.Description != "" {
+			s["description"] = p.Description
+		}
+		if p.Default != nil {
+			s["default"] = p.Default
+		}
+		props[p.Name] = s
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
+
+// ParamsOf returns the params of a struct with map tags. The validate tag
+// `required` makes it required unless it has the default tag, and the tags
+// of default and description are used as they are.
+func ParamsOf(v any) []Param {
+	t := structType(reflect.TypeOf(v))
+	if t == nil {
+		return nil
+	}
+
+	var params []Param
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get(tagMap)
+		if !field.IsExported() || name == "" {
+			continue
+		}
+		p := Param{
+			Name:        name,
+			Type:        TypeName(field.Type),
+			Description: field.Tag.Get(tagDescription),
+		}
+		for _, rule := range strings.Split(field.Tag.Get(tagValidate), ",") {
+			if rule == labelRequired {
+				p.Required = true
+			}
+		}
+		if d, ok := field.Tag.Lookup(tagDefault); ok {
+			p.Default = defaultValue(d, p.Type)
+			p.Required = false
+		}
+		params = append(params, p)
+	}
+
+	return params
+}
+
+// FieldsOf returns the output fields of a struct with map tags, and the
+// fields of nested structs are joined by dots.
+func FieldsOf(v any) []Field {
+	return fieldsOf(reflect.TypeOf(v), "")
+}
+
+func fieldsOf(t reflect.Type, prefix string) []Field {
+	t = structType(t)
+	if t == nil {
+		return nil
+	}
+
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get(tagMap)
+		if !field.IsExported() || name == "" {
+			continue
+		}
+		if nested := fieldsOf(field.Type, prefix+name+"."); nested != nil && field.Type != timeType {
+			fields = append(fields, nested...)
+			continue
+		}
+		fields = append(fields, Field{
+			Name:        prefix + name,
+			Type:        TypeName(field.Type),
+			Description: field.Tag.Get(tagDescription),
+		})
+	}
+
+	return fields
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// TypeName returns the type of params and fields of a Go type
+func TypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == durationType || t == timeType {
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+		return "array"
+	case reflect.Interface:
+		return "any"
+	default:
+		return scalarType(t.Kind())
+	}
+}
+
+func structType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// defaultValue converts the default tag to the value of the type
+func defaultValue(s, typ string) any {
+	switch typ {
+	case "integer":
+		if n, err := strconv.Atoi(s); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	return s
+}
+
+func (m Manifest) toPB() *pb.Manifest {
+	res := &pb.Manifest{Name: m.Name, Version: m.Version, Description: m.Description}
+	for _, p := range m.Params {
+		param := &pb.Param{Name: p.Name, Type: p.Type, Required: p.Required, Description: p.Description}
+		if p.Default != nil {
+			// defaults that can't be encoded are dropped
+			param.Default, _ = structpb.NewValue(normalizeJSON(p.Default))
+		}
+		res.Params = append(res.Params, param)
+	}
+	for _, f := range m.Outputs {
+		res.Outputs = append(res.Outputs, &pb.Field{Name: f.Name, Type: f.Type, Description: f.Description})
+	}
+	return res
+}
+
+func manifestFromPB(m *pb.Manifest) *Manifest {
+	res := &Manifest{Name: m.Name, Version: m.Version, Description: m.Description}
+	for _, p := range m.Params {
+		param := Param{Name: p.Name, Type: p.Type, Required: p.Required, Description: p.Description}
+		if p.Default != nil {
+			param.Default = p.Default.AsInterface()
+		}
+		res.Params = append(res.Params, param)
+	}
+	for _, f := range m.Outputs {
+		res.Outputs = append(res.Outputs, Field{Name: f.Name, Type: f.Type, Description: f.Description})
+	}
+	return res
+}
@@ -0,0 +1,109 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type manifestParams struct {
+	Addr    string        `map:"addr" validate:"required" description:"Address"`
+	Method  string        `map:"method" validate:"required" default:"GET"`
+	Count   int           `map:"count" default:"3"`
+	TLS     bool          `map:"tls" default:"true"`
+	Timeout time.Duration `map:"timeout"`
+	To      []string      `map:"to"`
+	Body    []byte        `map:"body"`
+	Any     any           `map:"any"`
+	ignored string
+}
+
+type manifestResult struct {
+	Code int `map:"code" description:"Status code"`
+	Res  struct {
+		Headers map[string]string `map:"headers"`
+		At      time.Time         `map:"at"`
+	} `map:"res"`
+}
+
+func TestParamsOf(t *testing.T) {
+	got := ParamsOf(&manifestParams{})
+	expects := []Param{
+		{Name: "addr", Type: "string", Required: true, Description: "Address"},
+		{Name: "method", Type: "string", Default: "GET"},
+		{Name: "count", Type: "integer", Default: 3},
+		{Name: "tls", Type: "boolean", Default: true},
+		{Name: "timeout", Type: "string"},
+		{Name: "to", Type: "array"},
+		{Name: "body", Type: "string"},
+		{Name: "any", Type: "any"},
+	}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+
+	if got := ParamsOf(map[string]any{}); got != nil {
+		t.Errorf("expected no params of a map, got %#v", got)
+	}
+}
+
+func TestFieldsOf(t *testing.T) {
+	got := FieldsOf(manifestResult{})
+	expects := []Field{
+		{Name: "code", Type: "integer", Description: "Status code"},
+		{Name: "res.headers", Type: "object"},
+		{Name: "res.at", Type: "string"},
+	}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+}
+
+func TestManifest_WithSchema(t *testing.T) {
+	m := Manifest{Params: []Param{
+		{Name: "addr", Type: "string", Required: true, Description: "Address"},
+		{Name: "count", Type: "integer", Default: 3},
+		{Name: "body", Type: "any"},
+	}}
+
+	got := m.WithSchema()
+	expects := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"addr":  map[string]any{"type": "string", "description": "Address"},
+			"count": map[string]any{"type": []string{"integer", "string"}, "default": 3},
+			"body":  map[string]any{},
+		},
+		"additionalProperties": false,
+	}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+
+	empty := Manifest{}
+	if got := empty.WithSchema(); !reflect.DeepEqual(got, map[string]any{"type": "object"}) {
+		t.Errorf("expected any keys without params, got %#v", got)
+	}
+}
+
+func TestManifest_PB(t *testing.T) {
+	m := Manifest{
+		Name:        "test",
+		Version:     "1.2.3",
+		Description: "Test action",
+		Params: []Param{
+			{Name: "addr", Type: "string", Required: true},
+			{Name: "count", Type: "integer", Default: 3},
+			{Name: "to", Type: "array", Default: []string{"a", "b"}},
+		},
+		Outputs: []Field{{Name: "res.code", Type: "integer"}},
+	}
+
+	got := manifestFromPB(m.toPB())
+	expects := m
+	expects.Params[1].Default = float64(3)
+	expects.Params[2].Default = []any{"a", "b"}
+	if !reflect.DeepEqual(got, &expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", &expects, got)
+	}
+}
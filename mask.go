@@ -0,0 +1,210 @@
+package probe
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maskText = "***"
+
+// secretEnvPatterns are parts of env names treated as secrets without declaration
+var secretEnvPatterns = []string{"TOKEN", "PASSWORD", "SECRET"}
+
+// minSecretLen is the length of values of env detected by the names to be
+// masked, since short values such as "1" would mask every occurrence.
+const minSecretLen = 4
+
+// Masker redacts secret values in outputs
+type Masker struct {
+	replacer *strings.Replacer
+}
+
+func NewMasker(secrets []string) *Masker {
+	seen := map[string]bool{}
+	var values []string
+	add := func(v string) {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+
+	for _, s := range secrets {
+		add(s)
+		// as printed by %#v or %q
+		q := strconv.Quote(s)
+		add(q[1 : len(q)-1])
+	}
+
+	if len(values) == 0 {
+		return &Masker{}
+	}
+
+	// the longer is replaced first
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	var oldnew []string
+	for _, v := range values {
+		oldnew = append(oldnew, v, maskText)
+	}
+
+	return &Masker{replacer: strings.NewReplacer(oldnew...)}
+}
+
+func (m *Masker) Mask(s string) string {
+	if m == nil || m.replacer == nil {
+		return s
+	}
+	return m.replacer.Replace(s)
+}
+
+// MaskValue returns a copy of v in which secrets of strings are masked
+func (m *Masker) MaskValue(v any) any {
+	switch vv := v.(type) {
+	case string:
+		return m.Mask(vv)
+	case map[string]any:
+		return m.MaskMap(vv)
+	case map[string]string:
+		res := make(map[string]string, len(vv))
+		for k, val := range vv {
+			res[k] = m.Mask(val)
+		}
+		return res
+	case []any:
+		res := make([]any, len(vv))
+		for i, val := range vv {
+			res[i] = m.MaskValue(val)
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+func (m *Masker) MaskMap(v map[string]any) map[string]any {
+	if v == nil {
+		return nil
+	}
+	res := make(map[string]any, len(v))
+	for k, val := range v {
+		res[k] = m.MaskValue(val)
+	}
+	return res
+}
+
+// MaskJobResult masks secrets in the step results
+func (m *Masker) MaskJobResult(r *JobResult) {
+	for _, s := range r.Steps {
+		s.Echo = m.Mask(s.Echo)
+		s.Error = m.Mask(s.Error)
+		if s.ActionError != nil {
+			s.ActionError.Message = m.Mask(s.ActionError.Message)
+		}
+		s.Req = m.MaskMap(s.Req)
+		s.Res = m.MaskMap(s.Res)
+		s.Outputs = m.MaskMap(s.Outputs)
+		for k, p := range s.Progress {
+			s.Progress[k].Message = m.Mask(p.Message)
+			s.Progress[k].Partial = m.MaskMap(p.Partial)
+		}
+		for _, e := range s.Expects {
+			e.Expect = m.Mask(e.Expect)
+			e.Error = m.Mask(e.Error)
+			e.Expected = m.MaskValue(e.Expected)
+			e.Actual = m.MaskValue(e.Actual)
+			e.Diagnosis = m.maskConditions(e.Diagnosis)
+		}
+		s.Diagnosis = m.maskConditions(s.Diagnosis)
+		if s.Snapshot != nil {
+			for i, line := range s.Snapshot.Diff {
+				s.Snapshot.Diff[i] = m.Mask(line)
+			}
+		}
+	}
+}
+
+func (m *Masker) maskConditions(conds []Condition) []Condition {
+	for i, c := range conds {
+		conds[i].Expr = m.Mask(c.Expr)
+		for k, o := range c.Operands {
+			conds[i].Operands[k] = Operand{Expr: m.Mask(o.Expr), Value: m.MaskValue(o.Value)}
+		}
+	}
+	return conds
+}
+
+// Writer returns a writer masking secrets written to w. Lines are masked
+// when they are completed, so secrets split across writes are masked, and
+// the rest is written by Flush.
+func (m *Masker) Writer(w io.Writer) io.Writer {
+	if m == nil || m.replacer == nil {
+		return w
+	}
+	return &maskWriter{w: w, m: m}
+}
+
+type maskWriter struct {
+	w   io.Writer
+	m   *Masker
+	buf []byte
+}
+
+func (mw *maskWriter) Write(p []byte) (int, error) {
+	mw.buf = append(mw.buf, p...)
+	i := bytes.LastIndexByte(mw.buf, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+
+	lines := string(mw.buf[:i+1])
+	mw.buf = append(mw.buf[:0], mw.buf[i+1:]...)
+	if _, err := io.WriteString(mw.w, mw.m.Mask(lines)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes the incomplete line
+func (mw *maskWriter) Flush() error {
+	if len(mw.buf) == 0 {
+		return nil
+	}
+	rest := string(mw.buf)
+	mw.buf = mw.buf[:0]
+	_, err := io.WriteString(mw.w, mw.m.Mask(rest))
+	return err
+}
+
+// secretValues returns values of declared secrets in env and vars,
+// and of env whose name looks like a secret, except short values.
+func secretValues(declared []string, env map[string]string, vars map[string]any) []string {
+	var values []string
+
+	for _, name := range declared {
+		if v, ok := env[name]; ok {
+			values = append(values, v)
+		}
+		if v, ok := vars[name].(string); ok {
+			values = append(values, v)
+		}
+	}
+
+	for name, v := range env {
+		if len(v) < minSecretLen {
+			continue
+		}
+		upper := strings.ToUpper(name)
+		for _, p := range secretEnvPatterns {
+			if strings.Contains(upper, p) {
+				values = append(values, v)
+				break
+			}
+		}
+	}
+
+	return values
+}
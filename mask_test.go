@@ -0,0 +1,89 @@
+package probe
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMasker(t *testing.T) {
+	m := NewMasker([]string{"s3cr3t", `p"w`, ""})
+
+	tests := map[string]string{
+		"Bearer s3cr3t":           "Bearer ***",
+		fmt.Sprintf("%#v", `p"w`): `"***"`,
+		"nothing":                 "nothing",
+	}
+
+	for input, expects := range tests {
+		if got := m.Mask(input); got != expects {
+			t.Errorf("\nExpected:\n%s\nGot:\n%s", expects, got)
+		}
+	}
+}
+
+func TestMaskerMaskMap(t *testing.T) {
+	m := NewMasker([]string{"s3cr3t"})
+	src := map[string]any{
+		"headers": map[string]string{"authorization": "Bearer s3cr3t"},
+		"list":    []any{"s3cr3t", 1},
+		"code":    200,
+	}
+
+	got := m.MaskMap(src)
+	expects := map[string]any{
+		"headers": map[string]string{"authorization": "Bearer ***"},
+		"list":    []any{"***", 1},
+		"code":    200,
+	}
+
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+	if src["list"].([]any)[0] != "s3cr3t" {
+		t.Error("source map should not be changed")
+	}
+}
+
+func TestMaskerWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewMasker([]string{"s3cr3t"}).Writer(&buf)
+	fmt.Fprintf(w, "token: %s\n", "s3cr3t")
+
+	if got := buf.String(); got != "token: ***\n" {
+		t.Errorf("unexpected output: %q", got)
+	}
+}
+
+func TestMaskerWriter_SplitWrites(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewMasker([]string{"s3cr3t"}).Writer(&buf)
+	fmt.Fprint(w, "token: s3c")
+	fmt.Fprint(w, "r3t\nnext: s3")
+	if got := buf.String(); got != "token: ***\n" {
+		t.Errorf("expected the completed line, got %q", got)
+	}
+
+	fmt.Fprint(w, "cr3t")
+	if err := w.(*maskWriter).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "token: ***\nnext: ***" {
+		t.Errorf("unexpected output: %q", got)
+	}
+}
+
+func TestSecretValues(t *testing.T) {
+	env := map[string]string{"GITHUB_TOKEN": "ghp_a", "db_password": "pass", "API_KEY": "c", "HOME": "d", "FOO_TOKEN": "1", "EMPTY_SECRET": ""}
+	vars := map[string]any{"pin": "e"}
+
+	got := secretValues([]string{"API_KEY", "pin"}, env, vars)
+	sort.Strings(got)
+
+	// short values of env detected by the names aren't secrets
+	if expects := []string{"c", "e", "ghp_a", "pass"}; !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+}
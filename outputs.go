@@ -0,0 +1,180 @@
+package probe
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"sync"
+)
+
+const (
+	defaultJSONPathFrom = "res.body"
+	defaultRegexFrom    = "res.rawbody ?? res.body"
+)
+
+// Output extracts a value after the action runs. It is an expression, or a
+// map of jsonpath or regex with the source expression:
+//
+//	outputs:
+//	  token: res.body.token
+//	  ids:
+//	    jsonpath: $.items[*].id
+//	  user_id:
+//	    regex: /users/(\d+)
+//	    from: res.headers.Location
+type Output struct {
+	Expr     string `yaml:"-"`
+	JSONPath string `yaml:"jsonpath"`
+	Regex    string `yaml:"regex"`
+	From     string `yaml:"from"`
+}
+
+func (o *Output) UnmarshalYAML(unmarshal func(any) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		*o = Output{Expr: s}
+		return nil
+	}
+
+	type output Output
+	var v output
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	if (v.JSONPath == "") == (v.Regex == "") {
+		return fmt.Errorf("output must have either jsonpath or regex")
+	}
+	*o = Output(v)
+
+	return nil
+}
+
+func (o Output) MarshalYAML() (any, error) {
+	if o.Expr != "" {
+		return o.Expr, nil
+	}
+	m := map[string]string{}
+	if o.JSONPath != "" {
+		m["jsonpath"] = o.JSONPath
+	}
+	if o.Regex != "" {
+		m["regex"] = o.Regex
+	}
+	if o.From != "" {
+		m["from"] = o.From
+	}
+	return m, nil
+}
+
+// JSONSchema returns the schema of an output for the workflow schema
+func (o Output) JSONSchema() map[string]any {
+	str := map[string]any{"type": "string"}
+	return map[string]any{
+		"oneOf": []any{
+			str,
+			map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{"jsonpath": str, "regex": str, "from": str},
+				"additionalProperties": false,
+			},
+		},
+	}
+}
+
+// Eval extracts the value with the test context
+func (o Output) Eval(env TestContext) (any, error) {
+	if o.Expr != "" {
+		return evalTemplateExpr(o.Expr, env)
+	}
+
+	from := o.From
+	if from == "" {
+		from = defaultJSONPathFrom
+		if o.Regex != "" {
+			from = defaultRegexFrom
+		}
+	}
+	src, err := evalTemplateExpr(from, env)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.JSONPath != "" {
+		v, err := JSONPath(src, o.JSONPath)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			return nil, fmt.Errorf("jsonpath %s is not found", o.JSONPath)
+		}
+		return v, nil
+	}
+
+	re, err := regexp.Compile(o.Regex)
+	if err != nil {
+		return nil, err
+	}
+	m := re.FindStringSubmatch(str(src))
+	switch {
+	case m == nil:
+		return nil, fmt.Errorf("regex %s does not match", o.Regex)
+	case len(m) > 1:
+		return m[1], nil
+	default:
+		return m[0], nil
+	}
+}
+
+// Outputs are the outputs of a step keyed by name
+type Outputs map[string]Output
+
+// Eval extracts all outputs and adds them to the outputs of env, so the
+// outputs already extracted are available to the following ones in order of
+// names.
+func (outs Outputs) Eval(env TestContext) (map[string]any, error) {
+	results := make(map[string]any, len(outs))
+	for _, name := range sortedKeys(outs) {
+		v, err := outs[name].Eval(env)
+		if err != nil {
+			return nil, fmt.Errorf("outputs.%s: %w", name, err)
+		}
+		results[name] = v
+		if env.Outputs != nil {
+			env.Outputs[name] = v
+		}
+	}
+	return results, nil
+}
+
+// sharedOutputs are the outputs of the steps of all jobs in a run, which
+// are available to the steps started after they are extracted.
+type sharedOutputs struct {
+	mu     sync.Mutex
+	values map[string]any
+}
+
+func newSharedOutputs() *sharedOutputs {
+	return &sharedOutputs{values: map[string]any{}}
+}
+
+func (s *sharedOutputs) add(values map[string]any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	maps.Copy(s.values, values)
+}
+
+// with returns a copy of the outputs overridden by own, which are the
+// outputs of the run of a job.
+func (s *sharedOutputs) with(own map[string]any) map[string]any {
+	res := map[string]any{}
+	if s != nil {
+		s.mu.Lock()
+		maps.Copy(res, s.values)
+		s.mu.Unlock()
+	}
+	maps.Copy(res, own)
+	return res
+}
@@ -0,0 +1,148 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestOutputsUnmarshalYAML(t *testing.T) {
+	src := `
+outputs:
+  token: res.body.token
+  ids:
+    jsonpath: $.items[*].id
+  user_id:
+    regex: /users/(\d+)
+    from: res.headers.Location
+`
+	var got struct {
+		Outputs Outputs `yaml:"outputs"`
+	}
+	if err := yaml.Unmarshal([]byte(src), &got); err != nil {
+		t.Fatalf("yaml.Unmarshal error %s", err)
+	}
+
+	expects := Outputs{
+		"token":   {Expr: "res.body.token"},
+		"ids":     {JSONPath: "$.items[*].id"},
+		"user_id": {Regex: `/users/(\d+)`, From: "res.headers.Location"},
+	}
+	if !reflect.DeepEqual(got.Outputs, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got.Outputs)
+	}
+
+	var invalid struct {
+		Outputs Outputs `yaml:"outputs"`
+	}
+	if err := yaml.Unmarshal([]byte("outputs:\n  x:\n    from: res.body"), &invalid); err == nil {
+		t.Error("expected error for the output without jsonpath or regex")
+	}
+}
+
+func TestOutputsEval(t *testing.T) {
+	env := TestContext{
+		Outputs: map[string]any{"prev": "p"},
+		Res: map[string]any{
+			"headers": map[string]string{"Location": "/users/42"},
+			"body":    map[string]any{"token": "abc", "items": []any{map[string]any{"id": 1}, map[string]any{"id": 2}}},
+			"rawbody": `{"token": "abc"}`,
+		},
+	}
+	outs := Outputs{
+		"a_token": {Expr: "res.body.token"},
+		"b_auth":  {Expr: `"Bearer " + outputs.a_token + outputs.prev`},
+		"ids":     {JSONPath: "$.items[*].id"},
+		"raw":     {Regex: `"token": "(\w+)"`},
+		"user_id": {Regex: `/users/(\d+)`, From: "res.headers.Location"},
+	}
+
+	got, err := outs.Eval(env)
+	if err != nil {
+		t.Fatalf("Eval error %s", err)
+	}
+	expects := map[string]any{
+		"a_token": "abc",
+		"b_auth":  "Bearer abcp",
+		"ids":     []any{1, 2},
+		"raw":     "abc",
+		"user_id": "42",
+	}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+	if env.Outputs["user_id"] != "42" {
+		t.Errorf("outputs should be added to env: %#v", env.Outputs)
+	}
+
+	for _, o := range []Output{{Expr: "res.body.missing"}, {JSONPath: "$.missing"}, {Regex: "xyz"}} {
+		if v, err := o.Eval(env); err == nil {
+			t.Errorf("%#v: expected error, but got %#v", o, v)
+		}
+	}
+}
+
+func TestRun_Outputs(t *testing.T) {
+	w, err := LoadWorkflow(strings.NewReader(`
+name: Outputs
+jobs:
+- name: Users
+  needs: [login]
+  steps:
+  - uses: echo
+    with:
+      msg: "{outputs.token}"
+    test: res.msg == "t0k3n"
+- name: Login
+  id: login
+  steps:
+  - uses: echo
+    with:
+      msg: t0k3n
+    outputs:
+      token: res.msg
+- name: Broken
+  steps:
+  - uses: echo
+    with:
+      msg: hi
+    test: res.msg == "bye"
+    expect:
+    - res.msg == "hi"
+    outputs:
+      missing: res.missing
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry()
+	r.Register("echo", inProcessEcho{log: hclog.NewNullLogger()})
+
+	var out bytes.Buffer
+	result, err := Run(context.Background(), w, WithOutput(&out), WithRegistry(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// outputs of the needed job are available to the job
+	if sr := result.Jobs[0].Steps[0]; sr.Status != StatusPassed {
+		t.Errorf("expected the outputs of login available, got %s: %s\n%s", sr.Status, sr.Error, out.String())
+	}
+	if sr := result.Jobs[1].Steps[0]; !reflect.DeepEqual(sr.Outputs, map[string]any{"token": "t0k3n"}) {
+		t.Errorf("expected the outputs in the result, got %#v", sr.Outputs)
+	}
+
+	// the checks are reported with the failed extraction
+	sr := result.Jobs[2].Steps[0]
+	if sr.Status != StatusFailed || len(sr.Expects) != 1 || len(sr.Diagnosis) == 0 {
+		t.Errorf("expected the checks evaluated, got %#v", sr)
+	}
+	if !strings.Contains(sr.Error, `res.msg == "bye"`) || !strings.Contains(sr.Error, "outputs.missing") {
+		t.Errorf("expected the errors of the test and the outputs, got %s", sr.Error)
+	}
+}
@@ -105,3 +105,193 @@ var Actions_ServiceDesc = grpc.ServiceDesc{
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pb/actions.proto",
 }
+
+const (
+	ActionsV2_Run_FullMethodName       = "/pb.ActionsV2/Run"
+	ActionsV2_RunStream_FullMethodName = "/pb.ActionsV2/RunStream"
+	ActionsV2_Describe_FullMethodName  = "/pb.ActionsV2/Describe"
+)
+
+// ActionsV2Client is the client API for ActionsV2 service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ActionsV2Client interface {
+	Run(ctx context.Context, in *RunV2Request, opts ...grpc.CallOption) (*RunV2Response, error)
+	RunStream(ctx context.Context, in *RunV2Request, opts ...grpc.CallOption) (ActionsV2_RunStreamClient, error)
+	Describe(ctx context.Context, in *DescribeRequest, opts ...grpc.CallOption) (*Manifest, error)
+}
+
+type actionsV2Client struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewActionsV2Client(cc grpc.ClientConnInterface) ActionsV2Client {
+	return &actionsV2Client{cc}
+}
+
+func (c *actionsV2Client) Run(ctx context.Context, in *RunV2Request, opts ...grpc.CallOption) (*RunV2Response, error) {
+	out := new(RunV2Response)
+	err := c.cc.Invoke(ctx, ActionsV2_Run_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *actionsV2Client) RunStream(ctx context.Context, in *RunV2Request, opts ...grpc.CallOption) (ActionsV2_RunStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ActionsV2_ServiceDesc.Streams[0], ActionsV2_RunStream_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &actionsV2RunStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ActionsV2_RunStreamClient interface {
+	Recv() (*RunEvent, error)
+	grpc.ClientStream
+}
+
+type actionsV2RunStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *actionsV2RunStreamClient) Recv() (*RunEvent, error) {
+	m := new(RunEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *actionsV2Client) Describe(ctx context.Context, in *DescribeRequest, opts ...grpc.CallOption) (*Manifest, error) {
+	out := new(Manifest)
+	err := c.cc.Invoke(ctx, ActionsV2_Describe_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ActionsV2Server is the server API for ActionsV2 service.
+// All implementations should embed UnimplementedActionsV2Server
+// for forward compatibility
+type ActionsV2Server interface {
+	Run(context.Context, *RunV2Request) (*RunV2Response, error)
+	RunStream(*RunV2Request, ActionsV2_RunStreamServer) error
+	Describe(context.Context, *DescribeRequest) (*Manifest, error)
+}
+
+// UnimplementedActionsV2Server should be embedded to have forward compatible implementations.
+type UnimplementedActionsV2Server struct {
+}
+
+func (UnimplementedActionsV2Server) Run(context.Context, *RunV2Request) (*RunV2Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Run not implemented")
+}
+func (UnimplementedActionsV2Server) RunStream(*RunV2Request, ActionsV2_RunStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method RunStream not implemented")
+}
+func (UnimplementedActionsV2Server) Describe(context.Context, *DescribeRequest) (*Manifest, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Describe not implemented")
+}
+
+// UnsafeActionsV2Server may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ActionsV2Server will
+// result in compilation errors.
+type UnsafeActionsV2Server interface {
+	mustEmbedUnimplementedActionsV2Server()
+}
+
+func RegisterActionsV2Server(s grpc.ServiceRegistrar, srv ActionsV2Server) {
+	s.RegisterService(&ActionsV2_ServiceDesc, srv)
+}
+
+func _ActionsV2_Run_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunV2Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ActionsV2Server).Run(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ActionsV2_Run_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ActionsV2Server).Run(ctx, req.(*RunV2Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ActionsV2_RunStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RunV2Request)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ActionsV2Server).RunStream(m, &actionsV2RunStreamServer{stream})
+}
+
+type ActionsV2_RunStreamServer interface {
+	Send(*RunEvent) error
+	grpc.ServerStream
+}
+
+type actionsV2RunStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *actionsV2RunStreamServer) Send(m *RunEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ActionsV2_Describe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DescribeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ActionsV2Server).Describe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ActionsV2_Describe_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ActionsV2Server).Describe(ctx, req.(*DescribeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ActionsV2_ServiceDesc is the grpc.ServiceDesc for ActionsV2 service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ActionsV2_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pb.ActionsV2",
+	HandlerType: (*ActionsV2Server)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Run",
+			Handler:    _ActionsV2_Run_Handler,
+		},
+		{
+			MethodName: "Describe",
+			Handler:    _ActionsV2_Describe_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "RunStream",
+			Handler:       _ActionsV2_RunStream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pb/actions.proto",
+}
@@ -0,0 +1,315 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+)
+
+// ActionPool keeps the plugin processes of actions during a run, keyed by
+// the name of the action. A process is started when the action is used
+// first, shared by the steps running concurrently, and started again when
+// it has exited. Actions in the registry run in the process without plugins,
+// and remote actions such as grpc://host:port/name are called over network.
+type ActionPool struct {
+	log hclog.Logger
+	// cmd returns the command starting the process of the action
+	cmd func(name string) (*exec.Cmd, error)
+	// specs pin the executables of actions
+	specs map[string]ActionSpec
+	// dir is the directory of the workflow, which the files of specs are
+	// relative to
+	dir      string
+	registry *Registry
+	mu       sync.Mutex
+	clients  map[string]*pooledAction
+}
+
+type pooledAction struct {
+	// ready is closed when the process is started or failed to start
+	ready  chan struct{}
+	client *plugin.Client
+	// conn is the connection of remote actions instead of the process
+	conn    *grpc.ClientConn
+	actions ActionsV2
+	err     error
+}
+
+func NewActionPool(out io.Writer, verbose bool) *ActionPool {
+	loglevel := hclog.Warn
+	if verbose {
+		loglevel = hclog.Debug
+	}
+
+	return &ActionPool{
+		log: hclog.New(&hclog.LoggerOptions{
+			Name:   "actions",
+			Output: out,
+			Level:  loglevel,
+		}),
+		cmd:     actionCmd,
+		clients: map[string]*pooledAction{},
+	}
+}
+
+// actionCmd returns the command of the builtin action, or the executable of
+// the external action.
+func actionCmd(name string) (*exec.Cmd, error) {
+	if isBuiltinAction(name) {
+		return exec.Command(os.Args[0], BuiltinCmd, name), nil
+	}
+
+	path, err := FindAction(name)
+	if err != nil {
+		return nil, NewActionError(ErrorValidation, err)
+	}
+
+	return exec.Command(path), nil
+}
+
+// Run runs the action with the process in the pool
+func (p *ActionPool) Run(ctx context.Context, name string, args []string, with map[string]any) (map[string]any, error) {
+	return p.RunStream(ctx, name, args, with, nil)
+}
+
+// RunStream runs the action with the process in the pool, and the progress
+// is called with the events reported by the action while running. The
+// action returns the error of ctx when ctx is done before it finishes.
+func (p *ActionPool) RunStream(ctx context.Context, name string, args []string, with map[string]any, progress func(Progress)) (map[string]any, error) {
+	actions, err := p.get(name)
+	if err != nil {
+		return nil, ClassifyError(err)
+	}
+
+	var result map[string]any
+	if ca, ok := actions.(ContextActions); ok {
+		result, err = ca.RunContext(ctx, args, with, progress)
+	} else {
+		result, err = runContext(ctx, actions, args, with, progress)
+	}
+	if err != nil {
+		return nil, ClassifyError(err)
+	}
+
+	return result, nil
+}
+
+// runContext runs the action and returns the error of ctx when ctx is done
+// first, since actions not canceled by ctx keep running. The progress is
+// serialized, as the events of plugins are by the stream, and dropped after
+// the return.
+func runContext(ctx context.Context, a ActionsV2, args []string, with map[string]any, progress func(Progress)) (map[string]any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	returned := false
+	report := func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !returned && progress != nil {
+			progress(p)
+		}
+	}
+	stop := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		returned = true
+	}
+
+	type ret struct {
+		result map[string]any
+		err    error
+	}
+	done := make(chan ret, 1)
+	go func() {
+		var r ret
+		// panics of actions in the process fail the step, not the run
+		defer func() {
+			if p := recover(); p != nil {
+				r = ret{err: NewActionError(ErrorProtocol, fmt.Errorf("action panicked: %v", p))}
+			}
+			done <- r
+		}()
+		switch impl := a.(type) {
+		case ContextActions:
+			r.result, r.err = impl.RunContext(ctx, args, with, report)
+		case StreamingActions:
+			r.result, r.err = impl.RunStream(args, with, report)
+		default:
+			r.result, r.err = a.Run(args, with)
+		}
+	}()
+
+	select {
+	case r := <-done:
+		stop()
+		return r.result, r.err
+	case <-ctx.Done():
+		stop()
+		return nil, ctx.Err()
+	}
+}
+
+// Manifest returns the manifest of the action, and nil for actions without
+// manifests such as v1 actions.
+func (p *ActionPool) Manifest(name string) (*Manifest, error) {
+	if a, ok := p.registry.Lookup(name); ok {
+		if ma, ok := a.(ManifestActions); ok {
+			m := ma.Manifest()
+			return &m, nil
+		}
+		return nil, nil
+	}
+
+	actions, err := p.get(name)
+	if err != nil {
+		return nil, ClassifyError(err)
+	}
+
+	c, ok := actions.(*ActionsV2Client)
+	if !ok {
+		return nil, nil
+	}
+	m, err := c.Describe()
+	if err != nil {
+		return nil, ClassifyError(err)
+	}
+
+	return m, nil
+}
+
+func (p *ActionPool) get(name string) (ActionsV2, error) {
+	if a, ok := p.registry.Lookup(name); ok {
+		if la, ok := a.(LoggingActions); ok {
+			a = la.WithLogger(p.log.Named(name))
+		}
+		return &inProcessActions{impl: a}, nil
+	}
+
+	p.mu.Lock()
+	pa, ok := p.clients[name]
+	if ok && !pa.stale() {
+		p.mu.Unlock()
+		<-pa.ready
+		return pa.actions, pa.err
+	}
+	if ok {
+		pa.kill()
+	}
+	pa = &pooledAction{ready: make(chan struct{})}
+	p.clients[name] = pa
+	p.mu.Unlock()
+
+	// the others wait for ready instead of starting another process
+	pa.start(name, p)
+	close(pa.ready)
+
+	return pa.actions, pa.err
+}
+
+// Close kills all processes in the pool
+func (p *ActionPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for name, pa := range p.clients {
+		<-pa.ready
+		pa.kill()
+		delete(p.clients, name)
+	}
+}
+
+func (pa *pooledAction) start(name string, p *ActionPool) {
+	if isRemoteAction(name) {
+		pa.connect(name, p.specs[name], p.dir)
+		return
+	}
+
+	cmd, err := p.cmd(name)
+	if err != nil {
+		pa.err = err
+		return
+	}
+
+	secure, err := p.specs[name].secureConfig()
+	if err != nil {
+		pa.err = NewActionError(ErrorValidation, fmt.Errorf("actions.%s: %w", name, err))
+		return
+	}
+
+	// the handshake is verified by go-plugin
+	pa.client = plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig:  Handshake,
+		VersionedPlugins: VersionedPluginMap,
+		Cmd:              cmd,
+		SecureConfig:     secure,
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolNetRPC, plugin.ProtocolGRPC},
+		Logger:           p.log,
+	})
+
+	protocol, err := pa.client.Client()
+	if errors.Is(err, plugin.ErrChecksumsDoNotMatch) {
+		pa.err = NewActionError(ErrorValidation, fmt.Errorf("action %s: %w", name, err))
+		return
+	}
+	if err != nil {
+		pa.err = fmt.Errorf("action %s: %w", name, err)
+		return
+	}
+
+	raw, err := protocol.Dispense("actions")
+	if err != nil {
+		pa.err = err
+		return
+	}
+
+	pa.actions = dispensedActions(raw)
+}
+
+// connect makes the client of the remote action, which is reconnected by
+// gRPC when the connection is lost.
+func (pa *pooledAction) connect(uses string, spec ActionSpec, dir string) {
+	r, err := parseRemoteAction(uses)
+	if err != nil {
+		pa.err = NewActionError(ErrorValidation, err)
+		return
+	}
+	if spec.SHA256 != "" {
+		pa.err = NewActionError(ErrorValidation, fmt.Errorf("actions.%s: sha256 can't pin remote actions", uses))
+		return
+	}
+
+	pa.conn, pa.actions, err = r.dial(spec, dir)
+	if err != nil {
+		pa.err = NewActionError(ErrorValidation, fmt.Errorf("actions.%s: %w", uses, err))
+	}
+}
+
+// stale reports whether the process has failed to start or exited
+func (pa *pooledAction) stale() bool {
+	select {
+	case <-pa.ready:
+		return pa.err != nil || pa.client != nil && pa.client.Exited()
+	default:
+		return false
+	}
+}
+
+func (pa *pooledAction) kill() {
+	if pa.client != nil {
+		pa.client.Kill()
+	}
+	if pa.conn != nil {
+		pa.conn.Close()
+	}
+}
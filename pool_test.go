@@ -0,0 +1,191 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/go-plugin"
+)
+
+func TestMain(m *testing.M) {
+	// the test binary serves the actions for the pool
+	if os.Getenv("PROBE_TEST_ACTIONS") == "1" {
+		plugin.Serve(&plugin.ServeConfig{
+			HandshakeConfig:  Handshake,
+			VersionedPlugins: VersionedPlugins(pidActions{}),
+			GRPCServer:       plugin.DefaultGRPCServer,
+		})
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+type pidActions struct{}
+
+func (pidActions) Run(args []string, with map[string]any) (map[string]any, error) {
+	return map[string]any{"pid": os.Getpid()}, nil
+}
+
+func (a pidActions) RunStream(args []string, with map[string]any, progress func(Progress)) (map[string]any, error) {
+	for i := 1; i <= 3; i++ {
+		progress(Progress{Message: "step", Count: int64(i), Total: 3, Partial: map[string]any{"i": i}})
+	}
+	return a.Run(args, with)
+}
+
+func (pidActions) Manifest() Manifest {
+	return Manifest{
+		Name:    "pid",
+		Version: "1.0.0",
+		Params:  []Param{{Name: "wait", Type: "integer", Default: 3}},
+		Outputs: []Field{{Name: "pid", Type: "integer", Description: "Process ID"}},
+	}
+}
+
+func newTestActionPool() *ActionPool {
+	pool := NewActionPool(io.Discard, false)
+	pool.cmd = func(name string) (*exec.Cmd, error) {
+		cmd := exec.Command(os.Args[0])
+		cmd.Env = append(os.Environ(), "PROBE_TEST_ACTIONS=1")
+		return cmd, nil
+	}
+	return pool
+}
+
+func TestActionPool(t *testing.T) {
+	pool := newTestActionPool()
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	pids := make([]any, 10)
+	for i := range pids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := pool.Run(context.Background(), "pid", nil, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			pids[i] = res["pid"]
+		}()
+	}
+	wg.Wait()
+
+	for _, pid := range pids {
+		if pid != pids[0] {
+			t.Errorf("expected one process, got pids %v", pids)
+			break
+		}
+	}
+
+	other, err := pool.Run(context.Background(), "other", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other["pid"] == pids[0] {
+		t.Errorf("expected another process for another action, got pid %v", other["pid"])
+	}
+}
+
+func TestActionPool_Close(t *testing.T) {
+	pool := newTestActionPool()
+	if _, err := pool.Run(context.Background(), "pid", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	client := pool.clients["pid"].client
+
+	pool.Close()
+
+	if !client.Exited() {
+		t.Error("expected the process to exit")
+	}
+	if len(pool.clients) != 0 {
+		t.Errorf("expected no clients, got %d", len(pool.clients))
+	}
+}
+
+func TestActionPool_Restart(t *testing.T) {
+	pool := newTestActionPool()
+	defer pool.Close()
+
+	first, err := pool.Run(context.Background(), "pid", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.clients["pid"].client.Kill()
+
+	second, err := pool.Run(context.Background(), "pid", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first["pid"] == second["pid"] {
+		t.Errorf("expected a new process, got pid %v", second["pid"])
+	}
+}
+
+func TestActionPool_RunStream(t *testing.T) {
+	pool := newTestActionPool()
+	defer pool.Close()
+
+	var got []Progress
+	res, err := pool.RunStream(context.Background(), "pid", nil, nil, func(p Progress) {
+		got = append(got, p)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res["pid"] == nil {
+		t.Errorf("expected the result, got %#v", res)
+	}
+
+	expects := []Progress{
+		{Message: "step", Count: 1, Total: 3, Partial: map[string]any{"i": float64(1)}},
+		{Message: "step", Count: 2, Total: 3, Partial: map[string]any{"i": float64(2)}},
+		{Message: "step", Count: 3, Total: 3, Partial: map[string]any{"i": float64(3)}},
+	}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+}
+
+func TestActionPool_Checksum(t *testing.T) {
+	pool := newTestActionPool()
+	defer pool.Close()
+
+	pool.specs = map[string]ActionSpec{
+		"pid": {SHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"},
+	}
+	_, err := pool.Run(context.Background(), "pid", nil, nil)
+
+	var ae *ActionError
+	if !errors.As(err, &ae) || ae.Category != ErrorValidation {
+		t.Errorf("expected a validation error, got %#v", err)
+	}
+}
+
+func TestActionPool_Manifest(t *testing.T) {
+	pool := newTestActionPool()
+	defer pool.Close()
+
+	got, err := pool.Manifest("pid")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expects := &Manifest{
+		Name:    "pid",
+		Version: "1.0.0",
+		Params:  []Param{{Name: "wait", Type: "integer", Default: float64(3)}},
+		Outputs: []Field{{Name: "pid", Type: "integer", Description: "Process ID"}},
+	}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+}
@@ -1,25 +1,43 @@
 package probe
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"io"
-	"io/ioutil"
 	"os"
 	"strings"
-
-	"github.com/go-playground/validator/v10"
-	"github.com/goccy/go-yaml"
+	"time"
 )
 
 type Probe struct {
 	FilePath string
 	workflow Workflow
 	config   Config
+	result   *WorkflowResult
 }
 
 type Config struct {
 	Log     io.Writer
 	Verbose bool
+	Filter  Filter
+	// Env overrides the process environment
+	Env  map[string]string
+	Vars map[string]any
+	// Secrets are decrypted values exposed only to expressions
+	Secrets map[string]any
+	// UpdateSnapshots rewrites the snapshot files with the responses
+	UpdateSnapshots bool
+	// Registry runs the registered actions in the process
+	Registry *Registry
+	// ctx cancels the steps not started yet
+	ctx context.Context
+}
+
+func (c Config) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
 }
 
 func New(path string, v bool) *Probe {
@@ -33,81 +51,50 @@ func New(path string, v bool) *Probe {
 }
 
 func (p *Probe) Do() error {
+	started := time.Now()
+	p.result = &WorkflowResult{Path: p.FilePath, Status: StatusPassed}
+	defer func() {
+		p.result.Duration = time.Since(started)
+	}()
+
 	if err := p.Load(); err != nil {
+		p.result.Status = StatusFailed
+		p.result.Error = err.Error()
+		var lerr *LoadError
+		if errors.As(err, &lerr) {
+			p.result.Error = lerr.Err.Error()
+		}
 		return err
 	}
 
-	p.workflow.Start(p.config)
+	p.result.Name = p.workflow.Name
+	p.result.Jobs = p.workflow.Start(p.config)
+	if p.workflow.exitStatus != 0 {
+		p.result.Status = StatusFailed
+	}
 
 	return nil
 }
 
+// Result returns the result of the last Do
+func (p *Probe) Result() *WorkflowResult {
+	return p.result
+}
+
 func (p *Probe) ExitStatus() int {
 	return p.workflow.exitStatus
 }
 
 func (p *Probe) Load() error {
-	y, err := ioutil.ReadFile(p.FilePath)
+	w, err := LoadWorkflowFile(p.FilePath)
 	if err != nil {
 		return err
 	}
-
-	v := validator.New()
-	dec := yaml.NewDecoder(bytes.NewReader(y), yaml.Validator(v))
-	if err = dec.Decode(&p.workflow); err != nil {
-		return err
-	}
-
-	p.setDefaultsToSteps()
+	p.workflow = *w
 
 	return nil
 }
 
-func (p *Probe) setDefaultsToSteps() {
-	for _, job := range p.workflow.Jobs {
-		if job.Defaults == nil {
-			continue
-		}
-
-		dataMap, ok := job.Defaults.(map[string]any)
-		if !ok {
-			continue
-		}
-
-		for key, values := range dataMap {
-			defaults, defok := values.(map[string]any)
-			if !defok {
-				continue
-			}
-
-			for _, s := range job.Steps {
-				if s.Uses != key {
-					continue
-				}
-				p.setDefaults(s.With, defaults)
-			}
-		}
-	}
-}
-
-func (p *Probe) setDefaults(data, defaults map[string]any) {
-	for key, defaultValue := range defaults {
-		// If key does not exist in data
-		if _, exists := data[key]; !exists {
-			data[key] = defaultValue
-			continue
-		}
-
-		// If you have a nested map with a key of data
-		if nestedDefault, ok := defaultValue.(map[string]any); ok {
-			if nestedData, ok := data[key].(map[string]any); ok {
-				// Recursively set default values
-				p.setDefaults(nestedData, nestedDefault)
-			}
-		}
-	}
-}
-
 func getEnvMap() map[string]string {
 	envmap := make(map[string]string)
 
@@ -1,7 +1,9 @@
 package probe
 
 import (
+	"errors"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/goccy/go-yaml"
@@ -28,3 +30,31 @@ func TestLoad(t *testing.T) {
 		t.Errorf("\nExpected:\n%s\nGot:\n%s", expects, got)
 	}
 }
+
+func TestLoadStrict(t *testing.T) {
+	tests := []struct {
+		path    string
+		expects string
+	}{
+		{path: "./testdata/unknown-field.yml", expects: `unknown field "wtih"`},
+		{path: "./testdata/invalid-repeat.yml", expects: `failed on the 'lt' tag`},
+		{path: "./testdata/missing-uses.yml", expects: `failed on the 'required' tag`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			p := New(tt.path, false)
+			err := p.Load()
+			if err == nil {
+				t.Fatal("expected error, but got nil")
+			}
+			var lerr *LoadError
+			if !errors.As(err, &lerr) {
+				t.Fatalf("expected LoadError, but got %#v", err)
+			}
+			if !strings.Contains(err.Error(), tt.expects) {
+				t.Errorf("\nExpected:\n%s\nGot:\n%s", tt.expects, err)
+			}
+		})
+	}
+}
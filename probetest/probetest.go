@@ -0,0 +1,189 @@
+// Package probetest runs workflows as Go subtests, so scenarios written in
+// YAML are run by `go test`:
+//
+//	func TestAPI(t *testing.T) {
+//		srv := httptest.NewServer(newHandler())
+//		defer srv.Close()
+//
+//		probetest.Run(t, "testdata/workflows/*.yml",
+//			probetest.WithBaseURL("http://localhost:8080", srv.URL),
+//			probetest.WithEnv(map[string]string{"API_TOKEN": "test"}),
+//		)
+//	}
+//
+// Each workflow, job and step is a subtest named as them, such as
+// TestAPI/Users/List_users/Get_users. A workflow is run once as `probe run`
+// does, and the results of its jobs and steps are reported by the subtests,
+// so `-run` selects the workflows to run and the jobs and steps to report.
+package probetest
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/linyows/probe"
+	"github.com/linyows/probe/actions"
+)
+
+// Option configures Run
+type Option func(*config)
+
+type config struct {
+	env      map[string]string
+	vars     map[string]any
+	registry *probe.Registry
+	baseURLs [][2]string
+}
+
+// WithEnv overrides the process environment of the workflows
+func WithEnv(env map[string]string) Option {
+	return func(c *config) { c.env = env }
+}
+
+// WithVars sets the variables of the workflows
+func WithVars(vars map[string]any) Option {
+	return func(c *config) { c.vars = vars }
+}
+
+// WithRegistry sets the actions run in the process, which are the builtin
+// actions by default. Actions not in the registry are run by plugins.
+func WithRegistry(r *probe.Registry) Option {
+	return func(c *config) { c.registry = r }
+}
+
+// WithBaseURL replaces the prefix from of `url` in `with` of the steps by to,
+// such as the URL of an httptest.Server. It can be given multiple times.
+func WithBaseURL(from, to string) Option {
+	return func(c *config) { c.baseURLs = append(c.baseURLs, [2]string{from, to}) }
+}
+
+// Run runs the workflows matched by the pattern as subtests of t. A pattern
+// is a file, a directory or a glob as the run command of probe.
+func Run(t *testing.T, pattern string, opts ...Option) {
+	t.Helper()
+
+	c := config{registry: actions.Builtin()}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	paths, err := probe.FindWorkflows([]string{pattern})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range paths {
+		w, err := probe.LoadWorkflowFile(path)
+		if err != nil {
+			t.Errorf("%s", err)
+			continue
+		}
+		rewriteURLs(w, c.baseURLs)
+
+		t.Run(w.Name, func(t *testing.T) {
+			runWorkflow(t, w, c)
+		})
+	}
+}
+
+// runWorkflow runs the workflow once, and reports the jobs and the steps as
+// subtests.
+func runWorkflow(t *testing.T, w *probe.Workflow, c config) {
+	var out bytes.Buffer
+	result, err := probe.Run(context.Background(), w,
+		probe.WithOutput(&out),
+		probe.WithEnv(c.env),
+		probe.WithVars(c.vars),
+		probe.WithRegistry(c.registry),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, job := range w.Jobs {
+		t.Run(job.Name, func(t *testing.T) {
+			reportJob(t, jobResults(w, result, i), out.String())
+		})
+	}
+}
+
+// reportJob reports the steps of the runs of the job as subtests
+func reportJob(t *testing.T, runs []*probe.JobResult, out string) {
+	for _, jr := range runs {
+		if jr.Status == probe.StatusSkipped {
+			t.Skipf("skipped: %s", jr.Reason)
+		}
+		for _, sr := range jr.Steps {
+			t.Run(sr.Name, func(t *testing.T) {
+				reportStep(t, sr)
+			})
+		}
+		if jr.Status == probe.StatusFailed {
+			t.Logf("output of the workflow:\n%s", out)
+		}
+	}
+}
+
+func reportStep(t *testing.T, sr *probe.StepResult) {
+	switch sr.Status {
+	case probe.StatusSkipped:
+		t.Skip("skipped")
+	case probe.StatusFailed:
+		t.Errorf("%s", stepMessage(sr))
+	}
+	if sr.Echo != "" {
+		t.Log(sr.Echo)
+	}
+}
+
+// stepMessage returns the failure message of the step
+func stepMessage(sr *probe.StepResult) string {
+	msg := sr.Error
+	if msg == "" {
+		msg = "failed"
+	}
+	if sr.Test != "" {
+		msg = "test: " + sr.Test + "\n" + msg
+	}
+	return "uses " + sr.Uses + ": " + msg
+}
+
+// jobResults returns the results of the job, since the results are ordered
+// by jobs and repeats.
+func jobResults(w *probe.Workflow, result *probe.WorkflowResult, index int) []*probe.JobResult {
+	offset := 0
+	for i, job := range w.Jobs {
+		runs := 1
+		if job.Repeat != nil {
+			runs = job.Repeat.Count
+		}
+		if i == index {
+			if offset+runs > len(result.Jobs) {
+				return nil
+			}
+			return result.Jobs[offset : offset+runs]
+		}
+		offset += runs
+	}
+	return nil
+}
+
+// rewriteURLs replaces the base URLs of `url` in `with` of the steps
+func rewriteURLs(w *probe.Workflow, baseURLs [][2]string) {
+	for _, job := range w.Jobs {
+		for _, st := range job.Steps {
+			u, ok := st.With["url"].(string)
+			if !ok {
+				continue
+			}
+			for _, b := range baseURLs {
+				if rest, ok := strings.CutPrefix(u, b[0]); ok {
+					st.With["url"] = b[1] + rest
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package probetest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/linyows/probe"
+)
+
+// newServer returns the server of users counting the requests of /users
+func newServer(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var listed atomic.Int64
+	mux := http.NewServeMux()
+	auth := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer test" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			h(w, r)
+		}
+	}
+	mux.HandleFunc("GET /users", auth(func(w http.ResponseWriter, r *http.Request) {
+		listed.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"users": []any{map[string]any{"id": 1, "name": "alice"}}})
+	}))
+	mux.HandleFunc("GET /users/1", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": 1, "name": "alice"})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &listed
+}
+
+func TestRun(t *testing.T) {
+	srv, listed := newServer(t)
+
+	Run(t, "testdata/*.yml",
+		WithBaseURL("http://localhost:8080", srv.URL),
+		WithEnv(map[string]string{"API_TOKEN": "test"}),
+		WithVars(map[string]any{"id": 1}),
+	)
+
+	// the job needed by Show user runs once as probe run
+	if n := listed.Load(); n != 1 {
+		t.Errorf("expected the request of the job sent once, got %d", n)
+	}
+}
+
+func TestRewriteURLs(t *testing.T) {
+	w := &probe.Workflow{Jobs: []probe.Job{{Steps: []probe.Step{
+		{Uses: "http", With: map[string]any{"url": "http://localhost:8080/api"}},
+		{Uses: "http", With: map[string]any{"url": "https://example.com"}},
+		{Uses: "hello", With: map[string]any{}},
+	}}}}
+
+	rewriteURLs(w, [][2]string{{"http://localhost:8080", "http://127.0.0.1:1234"}})
+
+	var got []any
+	for _, st := range w.Jobs[0].Steps {
+		got = append(got, st.With["url"])
+	}
+	expects := []any{"http://127.0.0.1:1234/api", "https://example.com", nil}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+}
+
+func TestJobResults(t *testing.T) {
+	w := &probe.Workflow{Jobs: []probe.Job{
+		{Name: "a"},
+		{Name: "b", Repeat: &probe.Repeat{Count: 2}},
+		{Name: "c"},
+	}}
+	result := &probe.WorkflowResult{Jobs: []*probe.JobResult{
+		{Name: "a"}, {Name: "b"}, {Name: "b"}, {Name: "c"},
+	}}
+
+	got := jobResults(w, result, 1)
+	if len(got) != 2 || got[0] != result.Jobs[1] || got[1] != result.Jobs[2] {
+		t.Errorf("expected the repeats of b, got %#v", got)
+	}
+	if got := jobResults(w, result, 2); len(got) != 1 || got[0].Name != "c" {
+		t.Errorf("expected c, got %#v", got)
+	}
+}
+
+func TestStepMessage(t *testing.T) {
+	sr := &probe.StepResult{Uses: "http", Test: "res.code == 200", Error: "test failed"}
+	expects := "uses http: test: res.code == 200\ntest failed"
+	if got := stepMessage(sr); got != expects {
+		t.Errorf("\nExpected:\n%s\nGot:\n%s", expects, got)
+	}
+}
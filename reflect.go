@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -24,8 +25,8 @@ func MergeStringMaps(base map[string]string, over map[string]any) map[string]str
 	}
 
 	for k, v := range over {
-		if value, ok := v.(string); ok {
-			res[k] = value
+		if v != nil {
+			res[k] = formatScalar(v)
 		}
 	}
 
@@ -77,11 +78,15 @@ func MapToStructByTags(params map[string]any, dest any) error {
 
 			// when the field is []byte
 		} else if field.Type() == reflect.TypeOf([]byte{}) {
-			v, ok := params[mapTag].(string)
+			v, ok := params[mapTag]
 			if !ok && validateTag == labelRequired {
 				return fmt.Errorf("expected string for field '%s' to convert to []byte", mapTag)
-			} else {
-				field.Set(reflect.ValueOf([]byte(v)))
+			} else if ok && v != nil {
+				switch v.(type) {
+				case map[string]any, []any:
+					return fmt.Errorf("expected string for field '%s' to convert to []byte, but got %T", mapTag, v)
+				}
+				field.Set(reflect.ValueOf([]byte(formatScalar(v))))
 			}
 
 		} else {
@@ -89,7 +94,9 @@ func MapToStructByTags(params map[string]any, dest any) error {
 			if v, ok := params[mapTag]; ok {
 				// set a value for a field
 				if field.CanSet() {
-					field.Set(reflect.ValueOf(v))
+					if err := setField(field, v); err != nil {
+						return fmt.Errorf("field '%s' %s", mapTag, err)
+					}
 				}
 
 				// error when required field is missing
@@ -102,6 +109,65 @@ func MapToStructByTags(params map[string]any, dest any) error {
 	return nil
 }
 
+// setField sets the value to the field, and converts strings, numbers and
+// bools to each other for the type of the field.
+func setField(field reflect.Value, v any) error {
+	if v == nil {
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Type().AssignableTo(field.Type()) {
+		field.Set(rv)
+		return nil
+	}
+
+	switch v.(type) {
+	case map[string]any, []any:
+		return fmt.Errorf("must be %s, but got %T", field.Type(), v)
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(formatScalar(v))
+		return nil
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(formatScalar(v))
+		if err != nil {
+			return fmt.Errorf("must be a bool, but got %v", v)
+		}
+		field.SetBool(b)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(formatScalar(v), 10, 64)
+		if err != nil || field.OverflowInt(n) {
+			return fmt.Errorf("must be an integer, but got %v", v)
+		}
+		field.SetInt(n)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(formatScalar(v), 64)
+		if err != nil {
+			return fmt.Errorf("must be a number, but got %v", v)
+		}
+		field.SetFloat(f)
+		return nil
+	}
+
+	return fmt.Errorf("must be %s, but got %T", field.Type(), v)
+}
+
+// formatScalar formats the value as %v, and floats without exponents
+func formatScalar(v any) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
 // converting from a struct to a map[string]any
 func StructToMapByTags(src any) (map[string]any, error) {
 	result := make(map[string]any)
@@ -238,7 +304,7 @@ func flattenIf(input any, prefix string) map[string]string {
 
 	default:
 		// If it is a basic type, it is stored as is.
-		res[prefix] = fmt.Sprintf("%v", input)
+		res[prefix] = formatScalar(input)
 	}
 
 	return res
@@ -297,6 +363,15 @@ func isJSON(st string) bool {
 	return (fChar == '{' && lChar == '}') || (fChar == '[' && lChar == ']')
 }
 
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func TitleCase(st string, char string) string {
 	parts := strings.Split(st, char)
 	for i, part := range parts {
@@ -80,6 +80,64 @@ func TestMapToStructByTags_Required(t *testing.T) {
 	}
 }
 
+func TestMapToStructByTags_Scalars(t *testing.T) {
+	got := TestStruct{}
+	params := map[string]any{
+		"string":   8080,
+		"number":   float64(3),
+		"bool":     "true",
+		"bytes":    42,
+		"required": 1.5,
+		"map_str_str": map[string]any{
+			"count": 3,
+			"ratio": 0.5,
+			"size":  float64(1000000),
+			"tls":   true,
+		},
+	}
+
+	expects := TestStruct{
+		String:   "8080",
+		Number:   3,
+		Bool:     true,
+		Bytes:    []byte("42"),
+		Required: "1.5",
+		MapStrStr: map[string]string{
+			"count": "3",
+			"ratio": "0.5",
+			"size":  "1000000",
+			"tls":   "true",
+		},
+	}
+
+	if err := MapToStructByTags(params, &got); err != nil {
+		t.Errorf("MapToStructByTags error %s", err)
+	}
+
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+}
+
+func TestMapToStructByTags_Invalid(t *testing.T) {
+	tests := []struct {
+		params map[string]any
+		err    string
+	}{
+		{params: map[string]any{"string": map[string]any{"a": 1}}, err: "field 'string' must be string, but got map[string]interface {}"},
+		{params: map[string]any{"number": 1.5}, err: "field 'number' must be an integer, but got 1.5"},
+		{params: map[string]any{"bool": "yes"}, err: "field 'bool' must be a bool, but got yes"},
+	}
+
+	for _, tt := range tests {
+		got := TestStruct{}
+		err := MapToStructByTags(tt.params, &got)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("\nExpected:\n%s\nGot:\n%v", tt.err, err)
+		}
+	}
+}
+
 func TestFlattenInterface(t *testing.T) {
 	expects := map[string]string{
 		"map_str_str__foo": "f-o-o",
@@ -0,0 +1,103 @@
+package probe
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// Registry holds actions run in the process of probe instead of plugin
+// processes, which is useful for tests and embedding. Actions not in the
+// registry are run by plugins.
+type Registry struct {
+	mu      sync.RWMutex
+	actions map[string]ActionsV2
+}
+
+// LoggingActions are in-process actions logging with the logger of the run.
+// WithLogger returns a copy of the action, since the action is shared by
+// runs.
+type LoggingActions interface {
+	ActionsV2
+	WithLogger(log hclog.Logger) ActionsV2
+}
+
+func NewRegistry() *Registry {
+	return &Registry{actions: map[string]ActionsV2{}}
+}
+
+// Register adds the action of the name, replacing the registered one
+func (r *Registry) Register(name string, a ActionsV2) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.actions[name] = a
+}
+
+// Lookup returns the action of the name
+func (r *Registry) Lookup(name string) (ActionsV2, bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	a, ok := r.actions[name]
+	return a, ok
+}
+
+// Names returns the names of the registered actions in order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.actions))
+	for name := range r.actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// inProcessActions passes copies of the values converted as by plugins, so
+// actions in the process get the same types and can't change the values of
+// steps.
+type inProcessActions struct {
+	impl ActionsV2
+}
+
+func (a *inProcessActions) Run(args []string, with map[string]any) (map[string]any, error) {
+	return a.RunContext(context.Background(), args, with, nil)
+}
+
+func (a *inProcessActions) RunStream(args []string, with map[string]any, progress func(Progress)) (map[string]any, error) {
+	return a.RunContext(context.Background(), args, with, progress)
+}
+
+// RunContext runs the action with ctx, and actions reporting progress from
+// multiple goroutines are serialized like plugins.
+func (a *inProcessActions) RunContext(ctx context.Context, args []string, with map[string]any, progress func(Progress)) (map[string]any, error) {
+	in, err := copyValues(with)
+	if err != nil {
+		return nil, NewActionError(ErrorValidation, err)
+	}
+
+	result, err := runContext(ctx, a.impl, args, in, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := copyValues(result)
+	if err != nil {
+		return nil, NewActionError(ErrorProtocol, err)
+	}
+	return out, nil
+}
+
+// copyValues converts the values through protobuf structs like plugins
+func copyValues(m map[string]any) (map[string]any, error) {
+	s, err := NewStruct(m)
+	if err != nil {
+		return nil, err
+	}
+	return StructToMap(s), nil
+}
@@ -0,0 +1,155 @@
+package probe
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+type inProcessEcho struct {
+	log hclog.Logger
+}
+
+func (a inProcessEcho) Run(args []string, with map[string]any) (map[string]any, error) {
+	a.log.Info("echo")
+	res := map[string]any{"msg": with["msg"], "n": with["n"]}
+	// changes of with don't affect the step
+	with["msg"] = "changed"
+	return map[string]any{"res": res}, nil
+}
+
+func (a inProcessEcho) WithLogger(log hclog.Logger) ActionsV2 {
+	a.log = log
+	return a
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.Register("echo", inProcessEcho{log: hclog.NewNullLogger()})
+	r.Register("pid", pidActions{})
+
+	if names := r.Names(); !reflect.DeepEqual(names, []string{"echo", "pid"}) {
+		t.Errorf("expected the names in order, got %v", names)
+	}
+
+	var nilRegistry *Registry
+	if _, ok := nilRegistry.Lookup("echo"); ok {
+		t.Error("expected nothing in the nil registry")
+	}
+}
+
+func TestWorkflow_StartInProcess(t *testing.T) {
+	r := NewRegistry()
+	r.Register("echo", inProcessEcho{log: hclog.NewNullLogger()})
+
+	with := map[string]any{"msg": "hi", "n": 1}
+	w := &Workflow{
+		Name: "in-process",
+		Jobs: []Job{{Name: "Echo", Steps: []Step{
+			{Name: "Echo", Uses: "echo", With: with, Test: `res.msg == "hi" && res.n == 1`},
+		}}},
+	}
+
+	var buf bytes.Buffer
+	results := w.Start(Config{Log: &buf, Verbose: true, Registry: r})
+
+	sr := results[0].Steps[0]
+	if sr.Status != StatusPassed {
+		t.Errorf("expected the step passed, got %s: %s\n%s", sr.Status, sr.Error, buf.String())
+	}
+	if expects := map[string]any{"msg": "hi", "n": float64(1)}; !reflect.DeepEqual(sr.Res, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, sr.Res)
+	}
+	if with["msg"] != "hi" {
+		t.Errorf("expected with of the step unchanged, got %#v", with)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("actions.echo: echo")) {
+		t.Errorf("expected the log of the action, got %s", buf.String())
+	}
+}
+
+func TestActionPool_RegistryManifest(t *testing.T) {
+	r := NewRegistry()
+	r.Register("pid", pidActions{})
+	r.Register("echo", inProcessEcho{})
+
+	pool := NewActionPool(&bytes.Buffer{}, false)
+	defer pool.Close()
+	pool.registry = r
+
+	m, err := pool.Manifest("pid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m == nil || m.Name != "pid" {
+		t.Errorf("expected the manifest of pid, got %#v", m)
+	}
+
+	m, err = pool.Manifest("echo")
+	if err != nil || m != nil {
+		t.Errorf("expected no manifest of echo, got %#v, %v", m, err)
+	}
+	if len(pool.clients) != 0 {
+		t.Errorf("expected no processes, got %d", len(pool.clients))
+	}
+}
+
+// concurrentProgress reports progress from goroutines like smtp sessions
+type concurrentProgress struct{}
+
+func (concurrentProgress) Run(args []string, with map[string]any) (map[string]any, error) {
+	return map[string]any{}, nil
+}
+
+func (concurrentProgress) RunStream(args []string, with map[string]any, progress func(Progress)) (map[string]any, error) {
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			progress(Progress{Message: "sent", Count: int64(i + 1), Total: 10})
+		}()
+	}
+	wg.Wait()
+	return map[string]any{}, nil
+}
+
+func TestInProcessActions_ConcurrentProgress(t *testing.T) {
+	a := &inProcessActions{impl: concurrentProgress{}}
+
+	// the progress isn't safe for concurrent use, as the one of steps
+	var events []Progress
+	_, err := a.RunStream(nil, map[string]any{}, func(p Progress) {
+		events = append(events, p)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 10 {
+		t.Errorf("expected 10 progress events, got %d", len(events))
+	}
+}
+
+// panicActions panics like actions assigning values of wrong types
+type panicActions struct{}
+
+func (panicActions) Run(args []string, with map[string]any) (map[string]any, error) {
+	var m map[string]any
+	m["res"] = with
+	return m, nil
+}
+
+func TestInProcessActions_Panic(t *testing.T) {
+	a := &inProcessActions{impl: panicActions{}}
+
+	_, err := a.Run(nil, map[string]any{})
+	var ae *ActionError
+	if !errors.As(err, &ae) || !strings.Contains(ae.Message, "action panicked") {
+		t.Errorf("expected the error of the panic, got %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/linyows/probe/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ActionMetadataKey is the gRPC metadata selecting the action of requests to
+// an ActionServer
+const ActionMetadataKey = "probe-action"
+
+// remoteAction is the action of uses in the forms of grpc://host:port/name,
+// grpcs://host:port/name with TLS and grpc+unix:///path/to.sock/name.
+type remoteAction struct {
+	target string
+	name   string
+	tls    bool
+}
+
+// isRemoteAction reports whether uses is the URL of a remote action
+func isRemoteAction(uses string) bool {
+	for _, scheme := range []string{"grpc://", "grpcs://", "grpc+unix://"} {
+		if strings.HasPrefix(uses, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRemoteAction(uses string) (*remoteAction, error) {
+	u, err := url.Parse(uses)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote action: %w", err)
+	}
+
+	dir, name := path.Split(u.Path)
+	if name == "" || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid remote action: %s has no action name", uses)
+	}
+
+	switch u.Scheme {
+	case "grpc", "grpcs":
+		if u.Host == "" || dir != "/" {
+			return nil, fmt.Errorf("invalid remote action: %s must be %s://host:port/name", uses, u.Scheme)
+		}
+		return &remoteAction{target: u.Host, name: name, tls: u.Scheme == "grpcs"}, nil
+	case "grpc+unix":
+		sock := strings.TrimSuffix(dir, "/")
+		if u.Host != "" || sock == "" {
+			return nil, fmt.Errorf("invalid remote action: %s must be grpc+unix:///path/to.sock/name", uses)
+		}
+		return &remoteAction{target: "unix://" + sock, name: name}, nil
+	}
+
+	return nil, fmt.Errorf("invalid remote action: unknown scheme %s", u.Scheme)
+}
+
+// dial returns the client of the remote action, which connects lazily
+func (r *remoteAction) dial(spec ActionSpec, dir string) (*grpc.ClientConn, *ActionsV2Client, error) {
+	creds := insecure.NewCredentials()
+	if r.tls {
+		cfg, err := spec.tlsConfig(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		creds = credentials.NewTLS(cfg)
+	}
+
+	conn, err := grpc.NewClient(r.target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conn, &ActionsV2Client{client: pb.NewActionsV2Client(conn), action: r.name}, nil
+}
+
+// tlsConfig returns the client config of grpcs, CA verifies the server
+// instead of the system roots, and Cert and Key are the client certificate
+// of mTLS. Relative files are in dir.
+func (s ActionSpec) tlsConfig(dir string) (*tls.Config, error) {
+	file := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if s.CA != "" {
+		pool, err := loadCertPool(file(s.CA))
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	if s.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(file(s.Cert), file(s.Key))
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// ServerTLSConfig returns the config of an ActionServer with the certificate,
+// and clients must have certificates signed by clientCA for mTLS unless it is
+// empty.
+func ServerTLSConfig(cert, key, clientCA string) (*tls.Config, error) {
+	c, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{c}}
+	if clientCA != "" {
+		pool, err := loadCertPool(clientCA)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates in %s", path)
+	}
+	return pool, nil
+}
+
+// ActionServer serves actions over network gRPC for remote runners using
+// them as grpc://host:port/name. The actions are run by plugins or the
+// registry on the server, and requests select them by the metadata of
+// ActionMetadataKey.
+type ActionServer struct {
+	// Actions are the names of the actions served
+	Actions []string
+	// Registry runs the registered actions in the process
+	Registry *Registry
+	Log      io.Writer
+	Verbose  bool
+
+	mu     sync.Mutex
+	pool   *ActionPool
+	server *grpc.Server
+}
+
+// Serve serves the actions on the listener until Close is called
+func (s *ActionServer) Serve(lis net.Listener, opts ...grpc.ServerOption) error {
+	s.mu.Lock()
+	if s.Log == nil {
+		s.Log = io.Discard
+	}
+	s.pool = NewActionPool(s.Log, s.Verbose)
+	s.pool.registry = s.Registry
+	s.server = grpc.NewServer(opts...)
+	pb.RegisterActionsV2Server(s.server, &remoteActionsServer{s: s})
+	server := s.server
+	s.mu.Unlock()
+
+	err := server.Serve(lis)
+	if errors.Is(err, grpc.ErrServerStopped) {
+		return nil
+	}
+	return err
+}
+
+// Close stops the server and the plugin processes of the actions
+func (s *ActionServer) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.server != nil {
+		s.server.GracefulStop()
+	}
+	if s.pool != nil {
+		s.pool.Close()
+	}
+}
+
+// action returns the name of the action of the request, which must be one
+// of the served actions.
+func (s *ActionServer) action(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	names := md.Get(ActionMetadataKey)
+	if len(names) == 0 {
+		return "", status.Errorf(codes.InvalidArgument, "metadata %s is missing", ActionMetadataKey)
+	}
+	for _, name := range s.Actions {
+		if name == names[0] {
+			return name, nil
+		}
+	}
+	return "", status.Errorf(codes.InvalidArgument, "action %s is not served", names[0])
+}
+
+type remoteActionsServer struct {
+	s *ActionServer
+}
+
+func (r *remoteActionsServer) Run(ctx context.Context, req *pb.RunV2Request) (*pb.RunV2Response, error) {
+	name, err := r.s.action(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return runResponse(r.s.pool.Run(ctx, name, req.Args, StructToMap(req.With))), nil
+}
+
+func (r *remoteActionsServer) RunStream(req *pb.RunV2Request, stream pb.ActionsV2_RunStreamServer) error {
+	name, err := r.s.action(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	res := runResponse(r.s.pool.RunStream(stream.Context(), name, req.Args, StructToMap(req.With), streamProgress(stream, &mu)))
+	mu.Lock()
+	defer mu.Unlock()
+	return stream.Send(&pb.RunEvent{Event: &pb.RunEvent_Response{Response: res}})
+}
+
+func (r *remoteActionsServer) Describe(ctx context.Context, req *pb.DescribeRequest) (*pb.Manifest, error) {
+	name, err := r.s.action(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m, err := r.s.pool.Manifest(name)
+	if err != nil {
+		return nil, statusOf(ClassifyError(err))
+	}
+	if m == nil {
+		return nil, status.Errorf(codes.Unimplemented, "action %s has no manifest", name)
+	}
+	return m.toPB(), nil
+}
+
+// statusOf returns the gRPC status of the action error for RPCs without the
+// error field, which is classified as the same category by ClassifyError.
+func statusOf(e *ActionError) error {
+	code := codes.Unknown
+	switch e.Category {
+	case ErrorConnection:
+		code = codes.Unavailable
+	case ErrorTimeout:
+		code = codes.DeadlineExceeded
+	case ErrorValidation:
+		code = codes.InvalidArgument
+	}
+	return status.Error(code, e.Message)
+}
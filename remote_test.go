@@ -0,0 +1,244 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+func TestParseRemoteAction(t *testing.T) {
+	tests := []struct {
+		uses    string
+		expects *remoteAction
+		err     string
+	}{
+		{uses: "grpc://localhost:7878/http", expects: &remoteAction{target: "localhost:7878", name: "http"}},
+		{uses: "grpcs://example.com:443/smtp", expects: &remoteAction{target: "example.com:443", name: "smtp", tls: true}},
+		{uses: "grpc+unix:///tmp/probe.sock/http", expects: &remoteAction{target: "unix:///tmp/probe.sock", name: "http"}},
+		{uses: "grpc://localhost:7878/", err: "has no action name"},
+		{uses: "grpc://localhost:7878/a/http", err: "must be grpc://host:port/name"},
+		{uses: "grpc+unix:///http", err: "must be grpc+unix:///path/to.sock/name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uses, func(t *testing.T) {
+			got, err := parseRemoteAction(tt.uses)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("expected the error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.expects) {
+				t.Errorf("\nExpected:\n%#v\nGot:\n%#v", tt.expects, got)
+			}
+		})
+	}
+}
+
+// serveActions starts the action server of echo, pid and wait on the listener
+func serveActions(t *testing.T, lis net.Listener, opts ...grpc.ServerOption) {
+	t.Helper()
+	r := NewRegistry()
+	r.Register("echo", inProcessEcho{log: hclog.NewNullLogger()})
+	r.Register("pid", pidActions{})
+	r.Register("wait", waitActions{})
+
+	s := &ActionServer{Actions: []string{"echo", "pid", "wait"}, Registry: r}
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(lis, opts...) }()
+	t.Cleanup(func() {
+		s.Close()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestActionServer(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveActions(t, lis)
+
+	pool := NewActionPool(&bytes.Buffer{}, false)
+	defer pool.Close()
+	base := "grpc://" + lis.Addr().String()
+
+	res, err := pool.Run(context.Background(), base+"/echo", nil, map[string]any{"msg": "hi", "n": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expects := map[string]any{"res": map[string]any{"msg": "hi", "n": float64(1)}}
+	if !reflect.DeepEqual(res, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, res)
+	}
+
+	var events []Progress
+	res, err = pool.RunStream(context.Background(), base+"/pid", nil, map[string]any{}, func(p Progress) {
+		events = append(events, p)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 || events[2].Count != 3 {
+		t.Errorf("expected 3 progress events, got %#v", events)
+	}
+	if res["pid"] != float64(os.Getpid()) {
+		t.Errorf("expected the action run by the server, got %#v", res)
+	}
+
+	m, err := pool.Manifest(base + "/pid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m == nil || m.Name != "pid" {
+		t.Errorf("expected the manifest of pid, got %#v", m)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = pool.Run(ctx, base+"/wait", nil, map[string]any{})
+	var ae *ActionError
+	if !errors.As(err, &ae) || ae.Category != ErrorTimeout {
+		t.Errorf("expected the remote action canceled by the deadline, got %v", err)
+	}
+
+	_, err = pool.Run(context.Background(), base+"/http", nil, map[string]any{})
+	if err == nil || !strings.Contains(err.Error(), "action http is not served") {
+		t.Errorf("expected the error of the action not served, got %v", err)
+	}
+	for name, pa := range pool.clients {
+		if pa.client != nil {
+			t.Errorf("expected no process of %s", name)
+		}
+	}
+}
+
+func TestActionServer_Unix(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "probe.sock")
+	lis, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveActions(t, lis)
+
+	w := &Workflow{
+		Name: "remote",
+		Jobs: []Job{{Name: "Echo", Steps: []Step{
+			{Name: "Echo", Uses: "grpc+unix://" + sock + "/echo", With: map[string]any{"msg": "hi"}, Test: `res.msg == "hi"`},
+		}}},
+	}
+
+	var buf bytes.Buffer
+	results := w.Start(Config{Log: &buf})
+	if sr := results[0].Steps[0]; sr.Status != StatusPassed {
+		t.Errorf("expected the step passed, got %s: %s\n%s", sr.Status, sr.Error, buf.String())
+	}
+}
+
+func TestActionServer_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := writeCert(t, dir, "ca", nil, nil)
+	writeCert(t, dir, "server", ca, caKey)
+	writeCert(t, dir, "client", ca, caKey)
+
+	cfg, err := ServerTLSConfig(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.crt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveActions(t, lis, grpc.Creds(credentials.NewTLS(cfg)))
+
+	uses := "grpcs://" + lis.Addr().String() + "/echo"
+	run := func(spec ActionSpec) error {
+		pool := NewActionPool(&bytes.Buffer{}, false)
+		defer pool.Close()
+		pool.specs = map[string]ActionSpec{uses: spec}
+		pool.dir = dir
+		_, err := pool.Run(context.Background(), uses, nil, map[string]any{"msg": "hi"})
+		return err
+	}
+
+	if err := run(ActionSpec{CA: "ca.crt", Cert: "client.crt", Key: "client.key"}); err != nil {
+		t.Errorf("expected the client with the certificate to run, got %v", err)
+	}
+	if err := run(ActionSpec{CA: "ca.crt"}); err == nil {
+		t.Error("expected the client without certificates to be rejected")
+	}
+}
+
+// writeCert writes name.crt and name.key signed by parent, or self-signed
+// CA without parent.
+func writeCert(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crt := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, name+".crt"), crt, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	k := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(filepath.Join(dir, name+".key"), k, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
@@ -0,0 +1,163 @@
+package probe
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+type Status string
+
+const (
+	StatusPassed  Status = "passed"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped"
+)
+
+type Report struct {
+	Workflows []*WorkflowResult `json:"workflows"`
+	Status    Status            `json:"status"`
+	Duration  time.Duration     `json:"duration"`
+}
+
+type WorkflowResult struct {
+	Path     string        `json:"path"`
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Jobs     []*JobResult  `json:"jobs"`
+	Duration time.Duration `json:"duration"`
+}
+
+type JobResult struct {
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Reason   string        `json:"reason,omitempty"`
+	Steps    []*StepResult `json:"steps"`
+	Duration time.Duration `json:"duration"`
+}
+
+type StepResult struct {
+	Index       int             `json:"index"`
+	Name        string          `json:"name"`
+	Uses        string          `json:"uses"`
+	Status      Status          `json:"status"`
+	Test        string          `json:"test,omitempty"`
+	Echo        string          `json:"echo,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	ActionError *ActionError    `json:"action_error,omitempty"`
+	Req         map[string]any  `json:"req,omitempty"`
+	Res         map[string]any  `json:"res,omitempty"`
+	Outputs     map[string]any  `json:"outputs,omitempty"`
+	Progress    []Progress      `json:"progress,omitempty"`
+	Snapshot    *SnapshotResult `json:"snapshot,omitempty"`
+	Expects     []*ExpectResult `json:"expects,omitempty"`
+	Diagnosis   []Condition     `json:"diagnosis,omitempty"`
+	Duration    time.Duration   `json:"duration"`
+}
+
+// ExpectResult is the result of an expectation
+type ExpectResult struct {
+	Expect    string      `json:"expect"`
+	Status    Status      `json:"status"`
+	Expected  any         `json:"expected"`
+	Actual    any         `json:"actual"`
+	Error     string      `json:"error,omitempty"`
+	Diagnosis []Condition `json:"diagnosis,omitempty"`
+}
+
+func (r *Report) Add(w *WorkflowResult) {
+	r.Workflows = append(r.Workflows, w)
+	if r.Status == "" {
+		r.Status = StatusPassed
+	}
+	if w.Status == StatusFailed {
+		r.Status = StatusFailed
+	}
+}
+
+func (r *Report) ExitStatus() int {
+	if r.Status == StatusFailed {
+		return 1
+	}
+	return 0
+}
+
+func (r *Report) Count(s Status) int {
+	n := 0
+	for _, w := range r.Workflows {
+		if w.Status == s {
+			n++
+		}
+	}
+	return n
+}
+
+func (w *WorkflowResult) Failed() bool {
+	return w.Status == StatusFailed
+}
+
+// Reporter writes a report in its own format
+type Reporter interface {
+	Report(w io.Writer, r *Report) error
+}
+
+var Reporters = map[string]Reporter{
+	"text": &TextReporter{},
+	"json": &JSONReporter{},
+}
+
+type TextReporter struct{}
+
+func (t *TextReporter) Report(w io.Writer, r *Report) error {
+	fmt.Fprintf(w, "\nSummary:\n")
+
+	for _, wf := range r.Workflows {
+		mark := color.GreenString("✔︎ ")
+		if wf.Failed() {
+			mark = color.RedString("✘ ")
+		}
+		fmt.Fprintf(w, "  %s %s %s\n", mark, wf.Path, color.HiBlackString(fmt.Sprintf("(%s)", wf.Duration.Round(time.Millisecond))))
+		if wf.Error != "" {
+			fmt.Fprintf(w, "       error: %s\n", firstLine(wf.Error))
+		}
+		for _, job := range wf.Jobs {
+			if job.Status == StatusSkipped {
+				fmt.Fprintf(w, "       %s\n", color.HiBlackString("- %s (skipped: %s)", job.Name, job.Reason))
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d workflows, %d passed, %d failed in %s\n",
+		len(r.Workflows), r.Count(StatusPassed), r.Count(StatusFailed), r.Duration.Round(time.Millisecond))
+	return err
+}
+
+type JSONReporter struct{}
+
+func (j *JSONReporter) Report(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func firstLine(s string) string {
+	if i := strings.Index(s, "\n"); i != -1 {
+		return s[:i]
+	}
+	return s
+}
+
+func (s *StepResult) fail(msg string) {
+	s.Status = StatusFailed
+	s.Error = msg
+}
+
+func (e *ExpectResult) fail(msg string) {
+	e.Status = StatusFailed
+	e.Error = msg
+}
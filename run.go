@@ -0,0 +1,208 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/goccy/go-yaml"
+)
+
+// LoadWorkflow decodes and validates the workflow in YAML, and applies the
+// defaults of jobs to the steps. Files referred by the workflow such as
+// schemas and snapshots are relative to the working directory, use
+// LoadWorkflowFile to make them relative to the workflow file.
+func LoadWorkflow(r io.Reader) (*Workflow, error) {
+	y, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return loadWorkflow("", y)
+}
+
+// LoadWorkflowFile loads the workflow of the file
+func LoadWorkflowFile(path string) (*Workflow, error) {
+	y, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return loadWorkflow(path, y)
+}
+
+func loadWorkflow(path string, y []byte) (*Workflow, error) {
+	var w Workflow
+	v := validator.New()
+	dec := yaml.NewDecoder(bytes.NewReader(y), yaml.Validator(v), yaml.Strict())
+	if err := dec.Decode(&w); err != nil {
+		return nil, &LoadError{Path: path, Err: err}
+	}
+	w.path = path
+
+	if err := w.validateNeeds(); err != nil {
+		return nil, err
+	}
+	if err := w.validateSnapshots(); err != nil {
+		return nil, err
+	}
+
+	w.setDefaultsToSteps()
+
+	return &w, nil
+}
+
+func (w *Workflow) setDefaultsToSteps() {
+	for _, job := range w.Jobs {
+		if job.Defaults == nil {
+			continue
+		}
+
+		dataMap, ok := job.Defaults.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		for key, values := range dataMap {
+			defaults, defok := values.(map[string]any)
+			if !defok {
+				continue
+			}
+
+			for _, s := range job.Steps {
+				if s.Uses != key {
+					continue
+				}
+				setDefaults(s.With, defaults)
+			}
+		}
+	}
+}
+
+func setDefaults(data, defaults map[string]any) {
+	for key, defaultValue := range defaults {
+		// If key does not exist in data
+		if _, exists := data[key]; !exists {
+			data[key] = defaultValue
+			continue
+		}
+
+		// If you have a nested map with a key of data
+		if nestedDefault, ok := defaultValue.(map[string]any); ok {
+			if nestedData, ok := data[key].(map[string]any); ok {
+				// Recursively set default values
+				setDefaults(nestedData, nestedDefault)
+			}
+		}
+	}
+}
+
+// Option configures Run
+type Option func(*runOptions)
+
+type runOptions struct {
+	config    Config
+	reporters []reportTo
+}
+
+type reportTo struct {
+	w io.Writer
+	r Reporter
+}
+
+// WithOutput writes the console output of the run to w, which is discarded
+// by default.
+func WithOutput(w io.Writer) Option {
+	return func(o *runOptions) { o.config.Log = w }
+}
+
+// WithVerbose shows the requests, the responses and the logs of actions
+func WithVerbose(v bool) Option {
+	return func(o *runOptions) { o.config.Verbose = v }
+}
+
+// WithReporter writes the report of the run to w by r after the run, and can
+// be given multiple times.
+func WithReporter(w io.Writer, r Reporter) Option {
+	return func(o *runOptions) { o.reporters = append(o.reporters, reportTo{w: w, r: r}) }
+}
+
+// WithEnv overrides the process environment
+func WithEnv(env map[string]string) Option {
+	return func(o *runOptions) { o.config.Env = env }
+}
+
+// WithVars sets the variables available as vars in expressions
+func WithVars(vars map[string]any) Option {
+	return func(o *runOptions) { o.config.Vars = vars }
+}
+
+// WithSecrets sets the secrets available as secrets in expressions
+func WithSecrets(secrets map[string]any) Option {
+	return func(o *runOptions) { o.config.Secrets = secrets }
+}
+
+// WithFilter selects the jobs and steps by tags and job names
+func WithFilter(f Filter) Option {
+	return func(o *runOptions) { o.config.Filter = f }
+}
+
+// WithRegistry runs the actions in the registry in the process
+func WithRegistry(r *Registry) Option {
+	return func(o *runOptions) { o.config.Registry = r }
+}
+
+// WithUpdateSnapshots rewrites the snapshot files with the responses
+func WithUpdateSnapshots(v bool) Option {
+	return func(o *runOptions) { o.config.UpdateSnapshots = v }
+}
+
+// Run runs the workflow and returns the result of the jobs and the steps. A
+// failed workflow is reported by the status of the result, and the error is
+// returned when the run is canceled by ctx or a reporter fails. Running
+// actions are canceled and the steps not started fail with the error of ctx,
+// and sdk actions get the cancel by their context. The workflow can be
+// run again, since the run doesn't change it.
+func Run(ctx context.Context, w *Workflow, opts ...Option) (*WorkflowResult, error) {
+	o := runOptions{config: Config{Log: io.Discard}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.config.ctx = ctx
+
+	started := time.Now()
+	wf := w.clone()
+	result := &WorkflowResult{Path: w.path, Name: w.Name, Status: StatusPassed}
+	result.Jobs = wf.Start(o.config)
+	if wf.exitStatus != 0 {
+		result.Status = StatusFailed
+	}
+	result.Duration = time.Since(started)
+
+	report := &Report{Duration: result.Duration}
+	report.Add(result)
+	for _, rt := range o.reporters {
+		if err := rt.r.Report(rt.w, report); err != nil {
+			return result, err
+		}
+	}
+
+	return result, ctx.Err()
+}
+
+// clone returns a copy of the workflow whose jobs and steps can be changed
+// by a run.
+func (w *Workflow) clone() *Workflow {
+	c := *w
+	c.exitStatus = 0
+	c.Jobs = make([]Job, len(w.Jobs))
+	for i, job := range w.Jobs {
+		job.Steps = append([]Step(nil), job.Steps...)
+		job.skipped = false
+		job.skipReason = ""
+		job.skipSteps = nil
+		c.Jobs[i] = job
+	}
+	return &c
+}
@@ -0,0 +1,272 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+const runWorkflow = `
+name: Library
+jobs:
+- name: Echo
+  defaults:
+    echo:
+      n: 1
+  steps:
+  - name: Hi
+    uses: echo
+    with:
+      msg: "{vars.greeting}"
+    test: res.msg == "hi" && res.n == 1
+`
+
+func TestLoadWorkflow(t *testing.T) {
+	w, err := LoadWorkflow(strings.NewReader(runWorkflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Name != "Library" {
+		t.Errorf("expected the name, got %q", w.Name)
+	}
+	if n := w.Jobs[0].Steps[0].With["n"]; n != uint64(1) {
+		t.Errorf("expected the defaults applied, got %#v", n)
+	}
+
+	_, err = LoadWorkflow(strings.NewReader("name: x\njobs:\n- name: j\n  stpes: []\n"))
+	var lerr *LoadError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("expected LoadError, but got %#v", err)
+	}
+	if !strings.Contains(err.Error(), `unknown field "stpes"`) {
+		t.Errorf("expected the unknown field, got %s", err)
+	}
+
+	// jobs without names are named Unknown Job by the run
+	if _, err := LoadWorkflow(strings.NewReader("name: x\njobs:\n- steps:\n  - uses: echo\n")); err != nil {
+		t.Errorf("expected the job without name to load, got %s", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	w, err := LoadWorkflow(strings.NewReader(runWorkflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry()
+	r.Register("echo", inProcessEcho{log: hclog.NewNullLogger()})
+
+	// the workflow can be run more than once
+	for i := 0; i < 2; i++ {
+		var out, report bytes.Buffer
+		result, err := Run(context.Background(), w,
+			WithOutput(&out),
+			WithVars(map[string]any{"greeting": "hi"}),
+			WithRegistry(r),
+			WithReporter(&report, &JSONReporter{}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Status != StatusPassed || result.Jobs[0].Steps[0].Status != StatusPassed {
+			t.Errorf("expected the workflow passed, got %#v\n%s", result.Jobs[0].Steps[0], out.String())
+		}
+		if !strings.Contains(out.String(), "Hi") {
+			t.Errorf("expected the output of the run, got %s", out.String())
+		}
+		if !strings.Contains(report.String(), `"name": "Library"`) {
+			t.Errorf("expected the report, got %s", report.String())
+		}
+	}
+}
+
+func TestRun_Canceled(t *testing.T) {
+	w, err := LoadWorkflow(strings.NewReader(runWorkflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry()
+	r.Register("echo", inProcessEcho{log: hclog.NewNullLogger()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := Run(ctx, w, WithRegistry(r))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the error of the context, got %v", err)
+	}
+	if result.Status != StatusFailed || result.Jobs[0].Steps[0].Error != context.Canceled.Error() {
+		t.Errorf("expected the steps failed by the cancel, got %#v", result.Jobs[0].Steps[0])
+	}
+}
+
+// waitActions blocks until the run is canceled
+type waitActions struct{}
+
+func (waitActions) Run(args []string, with map[string]any) (map[string]any, error) {
+	return nil, errors.New("run without context")
+}
+
+func (waitActions) RunContext(ctx context.Context, args []string, with map[string]any, progress func(Progress)) (map[string]any, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// hangActions blocks without the context until release is closed
+type hangActions struct {
+	release chan struct{}
+}
+
+func (a hangActions) Run(args []string, with map[string]any) (map[string]any, error) {
+	<-a.release
+	return map[string]any{}, nil
+}
+
+func TestRun_CanceledWhileRunning(t *testing.T) {
+	w, err := LoadWorkflow(strings.NewReader(`
+name: Blocking
+jobs:
+- name: Wait
+  steps:
+  - uses: wait
+- name: Hang
+  steps:
+  - uses: hang
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	defer close(release)
+	r := NewRegistry()
+	r.Register("wait", waitActions{})
+	r.Register("hang", hangActions{release: release})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	result, err := Run(ctx, w, WithRegistry(r))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the error of the deadline, got %v", err)
+	}
+	if d := time.Since(started); d > 5*time.Second {
+		t.Errorf("expected the actions interrupted, took %s", d)
+	}
+	for _, jr := range result.Jobs {
+		sr := jr.Steps[0]
+		if sr.Status != StatusFailed || sr.ActionError == nil || sr.ActionError.Category != ErrorTimeout {
+			t.Errorf("expected %s failed by the deadline, got %#v", jr.Name, sr)
+		}
+	}
+}
+
+// recordActions records the names of the runs in order
+type recordActions struct {
+	mu    *sync.Mutex
+	names *[]string
+}
+
+func (a recordActions) Run(args []string, with map[string]any) (map[string]any, error) {
+	// the job started first finishes last without the order of needs
+	if d, ok := with["sleep"].(string); ok {
+		dur, _ := time.ParseDuration(d)
+		time.Sleep(dur)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	*a.names = append(*a.names, with["name"].(string))
+	return map[string]any{}, nil
+}
+
+func TestRun_Needs(t *testing.T) {
+	w, err := LoadWorkflow(strings.NewReader(`
+name: Needs
+jobs:
+- name: Users
+  needs: [login]
+  steps:
+  - uses: record
+    with:
+      name: users
+- name: Login
+  id: login
+  steps:
+  - uses: record
+    with:
+      name: login
+      sleep: 50ms
+- name: Broken
+  id: broken
+  steps:
+  - uses: record
+    test: false
+    with:
+      name: broken
+- name: After broken
+  needs: [broken]
+  steps:
+  - uses: record
+    with:
+      name: after
+- name: Nightly
+  tags: [nightly]
+  steps:
+  - uses: record
+    with:
+      name: nightly
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var names []string
+	r := NewRegistry()
+	r.Register("record", recordActions{mu: &mu, names: &names})
+
+	var out, report bytes.Buffer
+	result, err := Run(context.Background(), w,
+		WithOutput(&out),
+		WithRegistry(r),
+		WithFilter(Filter{SkipTags: []string{"nightly"}}),
+		WithReporter(&report, &TextReporter{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login, users := slices.Index(names, "login"), slices.Index(names, "users")
+	if login < 0 || users < login {
+		t.Errorf("expected users after login, got %v", names)
+	}
+	if slices.Contains(names, "after") || slices.Contains(names, "nightly") {
+		t.Errorf("expected the skipped jobs not run, got %v", names)
+	}
+
+	reasons := map[string]string{}
+	for _, jr := range result.Jobs {
+		if jr.Status == StatusSkipped {
+			reasons[jr.Name] = jr.Reason
+		}
+	}
+	expects := map[string]string{"After broken": "needs broken failed", "Nightly": "filtered"}
+	if !reflect.DeepEqual(reasons, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, reasons)
+	}
+	for _, s := range []string{"After broken (skipped: needs broken failed)", "Nightly (skipped: filtered)"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("expected %q in the output, got %s", s, out.String())
+		}
+		if !strings.Contains(report.String(), "- "+s) {
+			t.Errorf("expected %q in the report, got %s", s, report.String())
+		}
+	}
+}
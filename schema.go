@@ -0,0 +1,204 @@
+package probe
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	schemaDraft = "http://json-schema.org/draft-07/schema#"
+	tagYAML     = "yaml"
+)
+
+// jsonSchemaer is implemented by types whose YAML differs from the fields
+type jsonSchemaer interface {
+	JSONSchema() map[string]any
+}
+
+// JSONSchema returns the JSON Schema of the workflow format.
+// withs holds the schemas of `with` keyed by action name, they are applied to
+// the steps that use the action.
+func JSONSchema(withs map[string]map[string]any) map[string]any {
+	defs := map[string]any{}
+	root := structSchema(reflect.TypeOf(Workflow{}), tagYAML, defs)
+
+	step, ok := defs["Step"].(map[string]any)
+	if ok && len(withs) > 0 {
+		names := make([]string, 0, len(withs))
+		for name := range withs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var conds []any
+		for _, name := range names {
+			ref := "with." + name
+			defs[ref] = withs[name]
+			conds = append(conds, map[string]any{
+				"if": map[string]any{
+					"properties": map[string]any{"uses": map[string]any{"const": name}},
+					"required":   []string{"uses"},
+				},
+				"then": map[string]any{
+					"properties": map[string]any{"with": map[string]any{"$ref": "#/definitions/" + ref}},
+				},
+			})
+		}
+		step["allOf"] = conds
+	}
+
+	root["$schema"] = schemaDraft
+	root["title"] = "Probe workflow"
+	root["definitions"] = defs
+
+	return root
+}
+
+// WithSchema returns the JSON Schema of `with` from a struct with map tags.
+// Since values may be filled by defaults or templates, nothing is required
+// and non-string scalars also accept strings.
+func WithSchema(v any) map[string]any {
+	return withSchemaOf(reflect.TypeOf(v))
+}
+
+func schemaOf(t reflect.Type, tag string, defs map[string]any) map[string]any {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if s, ok := reflect.Zero(t).Interface().(jsonSchemaer); ok {
+		return s.JSONSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		name := t.Name()
+		if _, exists := defs[name]; !exists {
+			// register first to stop recursion
+			defs[name] = map[string]any{}
+			defs[name] = structSchema(t, tag, defs)
+		}
+		return map[string]any{"$ref": "#/definitions/" + name}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string"}
+		}
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem(), tag, defs)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem(), tag, defs)}
+	case reflect.Interface:
+		return map[string]any{}
+	default:
+		return map[string]any{"type": scalarType(t.Kind())}
+	}
+}
+
+func structSchema(t reflect.Type, tag string, defs map[string]any) map[string]any {
+	props := map[string]any{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		s := schemaOf(field.Type, tag, defs)
+		rules := strings.Split(field.Tag.Get(tagValidate), ",")
+		for _, rule := range rules {
+			if rule == labelRequired {
+				required = append(required, name)
+			}
+		}
+		applyRules(s, rules)
+		props[name] = s
+	}
+
+	s := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+
+	return s
+}
+
+func withSchemaOf(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		props := map[string]any{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := field.Tag.Get(tagMap)
+			if !field.IsExported() || name == "" {
+				continue
+			}
+			props[name] = withSchemaOf(field.Type)
+		}
+		return map[string]any{"type": "object", "properties": props}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string"}
+		}
+		return map[string]any{"type": []string{"array", "string"}, "items": withSchemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": withSchemaOf(t.Elem())}
+	case reflect.Interface:
+		return map[string]any{}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	default:
+		return map[string]any{"type": []string{scalarType(t.Kind()), "string"}}
+	}
+}
+
+func scalarType(k reflect.Kind) string {
+	switch k {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+// applyRules converts the numeric rules of validate tags to keywords
+func applyRules(s map[string]any, rules []string) {
+	keywords := map[string]string{
+		"gte": "minimum",
+		"gt":  "exclusiveMinimum",
+		"lte": "maximum",
+		"lt":  "exclusiveMaximum",
+	}
+
+	for _, rule := range rules {
+		k, v, ok := strings.Cut(rule, "=")
+		if !ok {
+			continue
+		}
+		keyword, ok := keywords[k]
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(v); err == nil {
+			s[keyword] = n
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	with := WithSchema(&TestEmbedStruct{})
+	got := JSONSchema(map[string]map[string]any{"test": with})
+
+	defs := got["definitions"].(map[string]any)
+	if !reflect.DeepEqual(defs["with.test"], with) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", with, defs["with.test"])
+	}
+
+	step := defs["Step"].(map[string]any)
+	if step["additionalProperties"] != false {
+		t.Errorf("step should not allow unknown properties: %#v", step)
+	}
+	if expects := []string{"uses"}; !reflect.DeepEqual(step["required"], expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, step["required"])
+	}
+
+	repeat := defs["Repeat"].(map[string]any)
+	count := repeat["properties"].(map[string]any)["count"]
+	expects := map[string]any{"type": "integer", "minimum": 0, "exclusiveMaximum": 100}
+	if !reflect.DeepEqual(count, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, count)
+	}
+}
+
+func TestWithSchema(t *testing.T) {
+	got := WithSchema(&TestStruct{})
+	props := got["properties"].(map[string]any)
+
+	tests := map[string]map[string]any{
+		"string":      {"type": "string"},
+		"number":      {"type": []string{"integer", "string"}},
+		"bool":        {"type": []string{"boolean", "string"}},
+		"bytes":       {"type": "string"},
+		"map_str_str": {"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+	}
+
+	for name, expects := range tests {
+		if !reflect.DeepEqual(props[name], expects) {
+			t.Errorf("%s:\nExpected:\n%#v\nGot:\n%#v", name, expects, props[name])
+		}
+	}
+}
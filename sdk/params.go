@@ -0,0 +1,241 @@
+package sdk
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+const (
+	tagMap     = "map"
+	tagDefault = "default"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// Decode decodes `with` into dst, which is a pointer to a struct with map
+// tags, a map or any. Numbers, bools and strings are converted to each
+// other, and durations are parsed from strings such as `1m30s`. Fields
+// missing in `with` are set by their default tags.
+func Decode(with map[string]any, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("decode destination must be a non-nil pointer: %T", dst)
+	}
+	return decode("with", with, v.Elem())
+}
+
+func decode(key string, src any, dst reflect.Value) error {
+	if src == nil {
+		return nil
+	}
+
+	if dst.Type() == durationType {
+		if s, ok := src.(string); ok {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			dst.SetInt(int64(d))
+			return nil
+		}
+	}
+
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return decode(key, src, dst.Elem())
+
+	case reflect.Interface:
+		dst.Set(reflect.ValueOf(src))
+		return nil
+
+	case reflect.Struct:
+		m, ok := src.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s must be a map, but got %T", key, src)
+		}
+		t := dst.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name := t.Field(i).Tag.Get(tagMap)
+			if name == "" || !dst.Field(i).CanSet() {
+				continue
+			}
+			v, ok := m[name]
+			if !ok {
+				// missing keys are filled by the default tag
+				if v, ok = t.Field(i).Tag.Lookup(tagDefault); !ok {
+					continue
+				}
+			}
+			if err := decode(key+"."+name, v, dst.Field(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		m, ok := src.(map[string]any)
+		if !ok || dst.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%s must be a map, but got %T", key, src)
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMapWithSize(dst.Type(), len(m)))
+		}
+		for k, v := range m {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := decode(key+"."+k, v, elem); err != nil {
+				return err
+			}
+			dst.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), elem)
+		}
+		return nil
+
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			dst.SetBytes([]byte(fmt.Sprint(src)))
+			return nil
+		}
+		list, ok := src.([]any)
+		if !ok {
+			// a single value is a list of it
+			list = []any{src}
+		}
+		s := reflect.MakeSlice(dst.Type(), len(list), len(list))
+		for i, v := range list {
+			if err := decode(fmt.Sprintf("%s[%d]", key, i), v, s.Index(i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(s)
+		return nil
+
+	case reflect.String:
+		switch src.(type) {
+		case map[string]any, []any:
+			return fmt.Errorf("%s must be a string, but got %T", key, src)
+		}
+		dst.SetString(fmt.Sprint(src))
+		return nil
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(fmt.Sprint(src))
+		if err != nil {
+			return fmt.Errorf("%s must be a bool, but got %v", key, src)
+		}
+		dst.SetBool(b)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(numberString(src), 10, 64)
+		if err != nil || dst.OverflowInt(n) {
+			return fmt.Errorf("%s must be an integer, but got %v", key, src)
+		}
+		dst.SetInt(n)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(numberString(src), 10, 64)
+		if err != nil || dst.OverflowUint(n) {
+			return fmt.Errorf("%s must be an unsigned integer, but got %v", key, src)
+		}
+		dst.SetUint(n)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(numberString(src), 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number, but got %v", key, src)
+		}
+		dst.SetFloat(f)
+		return nil
+	}
+
+	return fmt.Errorf("%s: unsupported type %s", key, dst.Type())
+}
+
+// numberString returns the number as a string without exponents
+func numberString(v any) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// Encode encodes the result into a map. Fields of structs are keyed by their
+// map tags, and []byte is encoded as a string.
+func Encode(result any) (map[string]any, error) {
+	if result == nil {
+		return map[string]any{}, nil
+	}
+	v := encode(reflect.ValueOf(result))
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("result must be a struct or a map, but got %T", result)
+	}
+	return m, nil
+}
+
+func encode(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return encode(v.Elem())
+
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).Format(time.RFC3339Nano)
+		}
+		m := map[string]any{}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name := t.Field(i).Tag.Get(tagMap)
+			if name == "" || !t.Field(i).IsExported() {
+				continue
+			}
+			m[name] = encode(v.Field(i))
+		}
+		return m
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		m := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = encode(iter.Value())
+		}
+		return m
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		list := make([]any, v.Len())
+		for i := range list {
+			list[i] = encode(v.Index(i))
+		}
+		return list
+	}
+
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+
+	return v.Interface()
+}
@@ -0,0 +1,241 @@
+// Package sdk serves actions of probe from a function with typed params and
+// results, without the boilerplate of plugins:
+//
+//	type Params struct {
+//		Channel string `map:"channel" validate:"required"`
+//		Text    string `map:"text"`
+//	}
+//
+//	type Result struct {
+//		ID string `map:"id"`
+//	}
+//
+//	func main() {
+//		sdk.Serve("slack", func(ctx context.Context, p Params) (Result, error) {
+//			sdk.Logger(ctx).Info("posting", "channel", p.Channel)
+//			return Result{ID: "..."}, nil
+//		})
+//	}
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"github.com/linyows/probe"
+)
+
+// Func runs an action with the params decoded from `with`, and the result
+// is encoded by the map tags of its fields.
+type Func[P, R any] func(ctx context.Context, params P) (R, error)
+
+// Action is the action running a Func, which implements probe.ActionsV2,
+// probe.StreamingActions, probe.ManifestActions and probe.LoggingActions.
+// It is served as a plugin by Serve, or registered to probe.Registry to run
+// in the process.
+type Action[P, R any] struct {
+	name     string
+	fn       Func[P, R]
+	log      hclog.Logger
+	validate *validator.Validate
+	manifest probe.Manifest
+}
+
+// Option sets the manifest of the action
+type Option func(*probe.Manifest)
+
+// Version sets the version of the action
+func Version(v string) Option {
+	return func(m *probe.Manifest) { m.Version = v }
+}
+
+// Description sets the description of the action
+func Description(s string) Option {
+	return func(m *probe.Manifest) { m.Description = s }
+}
+
+// Params sets the params, which are derived from the struct of P by default
+func Params(params ...probe.Param) Option {
+	return func(m *probe.Manifest) { m.Params = params }
+}
+
+// Outputs sets the output fields, which are derived from the struct of R by
+// default.
+func Outputs(fields ...probe.Field) Option {
+	return func(m *probe.Manifest) { m.Outputs = fields }
+}
+
+// New returns the action running fn
+func New[P, R any](name string, fn Func[P, R], opts ...Option) *Action[P, R] {
+	var (
+		params P
+		result R
+	)
+	manifest := probe.Manifest{
+		Name:    name,
+		Params:  probe.ParamsOf(params),
+		Outputs: probe.FieldsOf(result),
+	}
+	for _, opt := range opts {
+		opt(&manifest)
+	}
+
+	return &Action[P, R]{
+		name: name,
+		fn:   fn,
+		log: hclog.New(&hclog.LoggerOptions{
+			Name:       name,
+			Level:      hclog.Debug,
+			Output:     os.Stderr,
+			JSONFormat: true,
+		}),
+		validate: newValidator(),
+		manifest: manifest,
+	}
+}
+
+// Manifest returns the manifest of the action
+func (a *Action[P, R]) Manifest() probe.Manifest {
+	return a.manifest
+}
+
+// WithLogger returns a copy of the action logging with log, which is used
+// when the action runs in the process of probe.
+func (a *Action[P, R]) WithLogger(log hclog.Logger) probe.ActionsV2 {
+	c := *a
+	c.log = log
+	return &c
+}
+
+// newValidator returns the validator naming fields by the map tags
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		return f.Tag.Get(tagMap)
+	})
+	return v
+}
+
+// validationMessage returns the messages of the failed fields of with
+func validationMessage(err error) string {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err.Error()
+	}
+
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		// the namespace starts with the name of the struct
+		_, key, _ := strings.Cut(e.Namespace(), ".")
+		if e.Tag() == "required" {
+			msgs = append(msgs, fmt.Sprintf("with.%s is required", key))
+		} else {
+			msgs = append(msgs, fmt.Sprintf("with.%s failed on %s", key, strings.TrimSpace(e.Tag()+" "+e.Param())))
+		}
+	}
+	return strings.Join(msgs, ", ")
+}
+
+// Serve serves fn as the plugin of the action, and is called from main of
+// the executable.
+func Serve[P, R any](name string, fn Func[P, R], opts ...Option) {
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig:  probe.Handshake,
+		VersionedPlugins: probe.VersionedPlugins(New(name, fn, opts...)),
+		GRPCServer:       plugin.DefaultGRPCServer,
+	})
+}
+
+func (a *Action[P, R]) Run(args []string, with map[string]any) (map[string]any, error) {
+	return a.RunStream(args, with, nil)
+}
+
+// RunStream decodes and validates the params, and runs the func with the
+// context having the logger, the args and the progress.
+func (a *Action[P, R]) RunStream(args []string, with map[string]any, progress func(probe.Progress)) (map[string]any, error) {
+	return a.RunContext(context.Background(), args, with, progress)
+}
+
+// RunContext runs the action as RunStream, and the context of the func is
+// canceled with ctx, such as when the run is canceled.
+func (a *Action[P, R]) RunContext(ctx context.Context, args []string, with map[string]any, progress func(probe.Progress)) (map[string]any, error) {
+	a.log.Debug(fmt.Sprintf("received: %#v", with))
+
+	var params P
+	if err := Decode(with, &params); err != nil {
+		return nil, probe.NewActionError(probe.ErrorValidation, err)
+	}
+	if isStruct(reflect.TypeOf(params)) {
+		if err := a.validate.Struct(params); err != nil {
+			return nil, probe.NewActionError(probe.ErrorValidation, errors.New(validationMessage(err)))
+		}
+	}
+
+	ctx = context.WithValue(ctx, contextKey{}, &actionContext{
+		log:      a.log,
+		args:     args,
+		progress: progress,
+	})
+
+	result, err := a.fn(ctx, params)
+	if err != nil {
+		a.log.Debug(fmt.Sprintf("error: %#v", err))
+		return nil, err
+	}
+
+	m, err := Encode(result)
+	if err != nil {
+		return nil, probe.NewActionError(probe.ErrorProtocol, err)
+	}
+	a.log.Debug(fmt.Sprintf("return: %#v", m))
+
+	return m, nil
+}
+
+type contextKey struct{}
+
+type actionContext struct {
+	log      hclog.Logger
+	args     []string
+	progress func(probe.Progress)
+}
+
+func fromContext(ctx context.Context) *actionContext {
+	if c, ok := ctx.Value(contextKey{}).(*actionContext); ok {
+		return c
+	}
+	return &actionContext{log: hclog.NewNullLogger()}
+}
+
+// Logger returns the logger of the action, whose logs are shown by probe
+// with --verbose.
+func Logger(ctx context.Context) hclog.Logger {
+	return fromContext(ctx).log
+}
+
+// Args returns the args of the action
+func Args(ctx context.Context) []string {
+	return fromContext(ctx).args
+}
+
+// Report reports the progress of the action, which is shown live by probe.
+// It is safe to call from multiple goroutines.
+func Report(ctx context.Context, p probe.Progress) {
+	if c := fromContext(ctx); c.progress != nil {
+		c.progress(p)
+	}
+}
+
+func isStruct(t reflect.Type) bool {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t != nil && t.Kind() == reflect.Struct
+}
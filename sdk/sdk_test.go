@@ -0,0 +1,184 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/linyows/probe"
+)
+
+type params struct {
+	Addr    string            `map:"addr" validate:"required"`
+	Count   int               `map:"count"`
+	Ratio   float64           `map:"ratio"`
+	TLS     bool              `map:"tls"`
+	Timeout time.Duration     `map:"timeout"`
+	To      []string          `map:"to"`
+	Headers map[string]string `map:"headers"`
+	Body    []byte            `map:"body"`
+	Auth    *auth             `map:"auth"`
+	ignored string
+}
+
+type auth struct {
+	User string `map:"user"`
+}
+
+type result struct {
+	Code    int      `map:"code"`
+	Body    []byte   `map:"body"`
+	Tags    []string `map:"tags"`
+	Elapsed string   `map:"elapsed"`
+	skipped string
+}
+
+func TestDecode(t *testing.T) {
+	with := map[string]any{
+		"addr":    "localhost:25",
+		"count":   "3",
+		"ratio":   0.5,
+		"tls":     "true",
+		"timeout": "1m30s",
+		"to":      "alice@example.com",
+		"headers": map[string]any{"x-id": 123},
+		"body":    "hello",
+		"auth":    map[string]any{"user": "bob"},
+		"unknown": 1,
+	}
+
+	var got params
+	if err := Decode(with, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	expects := params{
+		Addr:    "localhost:25",
+		Count:   3,
+		Ratio:   0.5,
+		TLS:     true,
+		Timeout: 90 * time.Second,
+		To:      []string{"alice@example.com"},
+		Headers: map[string]string{"x-id": "123"},
+		Body:    []byte("hello"),
+		Auth:    &auth{User: "bob"},
+	}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		with    map[string]any
+		expects string
+	}{
+		{map[string]any{"count": "many"}, "with.count must be an integer, but got many"},
+		{map[string]any{"tls": 2}, "with.tls must be a bool, but got 2"},
+		{map[string]any{"auth": "bob"}, "with.auth must be a map, but got string"},
+		{map[string]any{"addr": []any{"a"}}, "with.addr must be a string, but got []interface {}"},
+	}
+
+	for _, tt := range tests {
+		var p params
+		err := Decode(tt.with, &p)
+		if err == nil || err.Error() != tt.expects {
+			t.Errorf("\nExpected:\n%s\nGot:\n%v", tt.expects, err)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	got, err := Encode(result{Code: 200, Body: []byte("ok"), Tags: []string{"a"}, Elapsed: "1s", skipped: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expects := map[string]any{"code": 200, "body": "ok", "tags": []any{"a"}, "elapsed": "1s"}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+
+	if _, err := Encode("text"); err == nil {
+		t.Error("expected an error of a result not a map")
+	}
+}
+
+func TestAction_RunStream(t *testing.T) {
+	a := New("test", func(ctx context.Context, p params) (result, error) {
+		Report(ctx, probe.Progress{Message: "connected"})
+		return result{Code: p.Count, Tags: Args(ctx)}, nil
+	})
+
+	var progress []probe.Progress
+	got, err := a.RunStream([]string{"x"}, map[string]any{"addr": "localhost:25", "count": 2}, func(p probe.Progress) {
+		progress = append(progress, p)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expects := map[string]any{"code": 2, "body": "", "tags": []any{"x"}, "elapsed": ""}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+	if len(progress) != 1 || progress[0].Message != "connected" {
+		t.Errorf("expected the progress, got %#v", progress)
+	}
+}
+
+func TestAction_Validation(t *testing.T) {
+	a := New("test", func(ctx context.Context, p params) (result, error) {
+		return result{}, nil
+	})
+
+	_, err := a.Run(nil, map[string]any{})
+
+	var ae *probe.ActionError
+	if !errors.As(err, &ae) || ae.Category != probe.ErrorValidation {
+		t.Fatalf("expected a validation error, got %#v", err)
+	}
+	if expects := "with.addr is required"; ae.Message != expects {
+		t.Errorf("\nExpected:\n%s\nGot:\n%s", expects, ae.Message)
+	}
+}
+
+func TestAction_Manifest(t *testing.T) {
+	type defaults struct {
+		Addr  string `map:"addr" validate:"required" description:"Address"`
+		Count int    `map:"count" default:"3"`
+	}
+	a := New("test", func(ctx context.Context, p defaults) (result, error) {
+		return result{Code: p.Count}, nil
+	}, Version("1.0.0"), Description("Test action"))
+
+	got := a.Manifest()
+	expects := probe.Manifest{
+		Name:        "test",
+		Version:     "1.0.0",
+		Description: "Test action",
+		Params: []probe.Param{
+			{Name: "addr", Type: "string", Required: true, Description: "Address"},
+			{Name: "count", Type: "integer", Default: 3},
+		},
+		Outputs: []probe.Field{
+			{Name: "code", Type: "integer"},
+			{Name: "body", Type: "string"},
+			{Name: "tags", Type: "array"},
+			{Name: "elapsed", Type: "string"},
+		},
+	}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+
+	res, err := a.Run(nil, map[string]any{"addr": "localhost:25"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res["code"] != 3 {
+		t.Errorf("expected the default of count, got %#v", res["code"])
+	}
+}
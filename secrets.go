@@ -0,0 +1,101 @@
+package probe
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/goccy/go-yaml"
+)
+
+// LoadSecretsFile decrypts an age encrypted yaml file of secrets.
+// The decrypted content is kept only in memory.
+func LoadSecretsFile(path string, identities ...age.Identity) (map[string]any, error) {
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("secrets file %s: key file or passphrase is required", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = bufio.NewReader(f)
+	if peek, _ := r.(*bufio.Reader).Peek(len(armor.Header)); string(peek) == armor.Header {
+		r = armor.NewReader(r)
+	}
+
+	dr, err := age.Decrypt(r, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("secrets file %s: %w", path, err)
+	}
+
+	b, err := io.ReadAll(dr)
+	if err != nil {
+		return nil, fmt.Errorf("secrets file %s: %w", path, err)
+	}
+
+	secrets := map[string]any{}
+	if err := yaml.Unmarshal(b, &secrets); err != nil {
+		return nil, fmt.Errorf("secrets file %s: %w", path, err)
+	}
+
+	return secrets, nil
+}
+
+// SecretsIdentities returns identities from an age key file and a passphrase,
+// either of them may be empty.
+func SecretsIdentities(keyFile, passphrase string) ([]age.Identity, error) {
+	var ids []age.Identity
+
+	if keyFile != "" {
+		b, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := age.ParseIdentities(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("key file %s: %w", keyFile, err)
+		}
+		ids = append(ids, keys...)
+	}
+
+	if passphrase != "" {
+		id, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// stringValues returns the values in nested maps and lists to be masked.
+// Numbers and bools are masked as formatted, since every leaf of the secrets
+// file is a secret.
+func stringValues(v any) []string {
+	switch vv := v.(type) {
+	case nil:
+		return nil
+	case map[string]any:
+		var values []string
+		for _, val := range vv {
+			values = append(values, stringValues(val)...)
+		}
+		return values
+	case []any:
+		var values []string
+		for _, val := range vv {
+			values = append(values, stringValues(val)...)
+		}
+		return values
+	default:
+		return []string{formatScalar(vv)}
+	}
+}
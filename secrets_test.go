@@ -0,0 +1,95 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestLoadSecretsFile(t *testing.T) {
+	expects := map[string]any{
+		"api": map[string]any{"token": "t0ps3cr3t"},
+		"pin": uint64(1234),
+	}
+
+	tests := []struct {
+		path       string
+		keyFile    string
+		passphrase string
+	}{
+		{path: "testdata/secrets/secrets.yml.age", keyFile: "testdata/secrets/key.txt"},
+		{path: "testdata/secrets/passphrase.yml.age", passphrase: "correct horse"},
+	}
+
+	for _, tt := range tests {
+		ids, err := SecretsIdentities(tt.keyFile, tt.passphrase)
+		if err != nil {
+			t.Fatalf("SecretsIdentities error %s", err)
+		}
+		got, err := LoadSecretsFile(tt.path, ids...)
+		if err != nil {
+			t.Fatalf("LoadSecretsFile error %s", err)
+		}
+		if !reflect.DeepEqual(got, expects) {
+			t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+		}
+	}
+}
+
+func TestLoadSecretsFile_WrongPassphrase(t *testing.T) {
+	ids, err := SecretsIdentities("", "wrong")
+	if err != nil {
+		t.Fatalf("SecretsIdentities error %s", err)
+	}
+	if _, err := LoadSecretsFile("testdata/secrets/passphrase.yml.age", ids...); err == nil {
+		t.Error("expected error, but got nil")
+	}
+	if _, err := LoadSecretsFile("testdata/secrets/passphrase.yml.age"); err == nil {
+		t.Error("expected error without identities, but got nil")
+	}
+}
+
+func TestStringValues(t *testing.T) {
+	got := stringValues(map[string]any{
+		"a": "s3cr3t",
+		"b": []any{"p4ssw0rd", uint64(5432), "x"},
+		"c": true,
+	})
+	sort.Strings(got)
+
+	if expects := []string{"5432", "p4ssw0rd", "s3cr3t", "true", "x"}; !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+}
+
+func TestRun_MaskSecrets(t *testing.T) {
+	w, err := LoadWorkflow(strings.NewReader(`
+name: Secrets
+jobs:
+- name: Echo
+  steps:
+  - name: Pin
+    uses: echo
+    with:
+      msg: "pin {secrets.pin}"
+    echo: res.msg
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry()
+	r.Register("echo", inProcessEcho{log: hclog.NewNullLogger()})
+
+	var out bytes.Buffer
+	if _, err := Run(context.Background(), w, WithOutput(&out), WithRegistry(r), WithSecrets(map[string]any{"pin": uint64(1234)})); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "1234") || !strings.Contains(out.String(), "pin ***") {
+		t.Errorf("expected the number of the secret masked, got %s", out.String())
+	}
+}
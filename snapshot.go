@@ -0,0 +1,288 @@
+package probe
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+const (
+	snapshotDir     = "__snapshots__"
+	snapshotIgnored = "<ignored>"
+)
+
+// snapshotHeaders are the headers recorded by default
+var snapshotHeaders = []string{"content-type"}
+
+// snapshotLocks serialize the checks of the same file by repeats of a job,
+// keyed by the path.
+var snapshotLocks sync.Map
+
+// Snapshot records the normalized response of a step into a file, and later
+// runs are compared with it. It is `snapshot: true` or a map:
+//
+//	snapshot:
+//	  headers: [content-type, cache-control]
+//	  ignore: [$.body.id, $.body.items[*].created_at]
+//
+// The paths of ignore are JSONPath of the recorded document, which has
+// status, headers and body.
+type Snapshot struct {
+	Headers []string `yaml:"headers"`
+	Ignore  []string `yaml:"ignore"`
+	enabled bool
+}
+
+func (s *Snapshot) UnmarshalYAML(unmarshal func(any) error) error {
+	var enabled bool
+	if err := unmarshal(&enabled); err == nil {
+		*s = Snapshot{enabled: enabled}
+		return nil
+	}
+
+	type snapshot Snapshot
+	var v snapshot
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	*s = Snapshot(v)
+	s.enabled = true
+
+	return nil
+}
+
+func (s Snapshot) MarshalYAML() (any, error) {
+	if len(s.Headers) == 0 && len(s.Ignore) == 0 {
+		return s.enabled, nil
+	}
+	return map[string][]string{"headers": s.Headers, "ignore": s.Ignore}, nil
+}
+
+// JSONSchema returns the schema of snapshot for the workflow schema
+func (s Snapshot) JSONSchema() map[string]any {
+	list := map[string]any{"type": "array", "items": map[string]any{"type": "string"}}
+	return map[string]any{
+		"oneOf": []any{
+			map[string]any{"type": "boolean"},
+			map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{"headers": list, "ignore": list},
+				"additionalProperties": false,
+			},
+		},
+	}
+}
+
+func (s *Snapshot) Enabled() bool {
+	return s != nil && s.enabled
+}
+
+// SnapshotResult is the result of comparison with the snapshot file
+type SnapshotResult struct {
+	Path    string   `json:"path"`
+	Status  Status   `json:"status"`
+	Written bool     `json:"written,omitempty"`
+	Diff    []string `json:"diff,omitempty"`
+}
+
+// Check compares the response with the snapshot file, and writes the file
+// when it does not exist or update is true. Secrets are masked in the file.
+func (s *Snapshot) Check(path string, res map[string]any, update bool, m *Masker) (*SnapshotResult, error) {
+	doc, err := s.document(res)
+	if err != nil {
+		return nil, err
+	}
+	doc = []byte(m.Mask(string(doc)))
+
+	mu, _ := snapshotLocks.LoadOrStore(path, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	defer mu.(*sync.Mutex).Unlock()
+
+	r := &SnapshotResult{Path: path, Status: StatusPassed}
+
+	recorded, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if update || os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, doc, 0644); err != nil {
+			return nil, err
+		}
+		r.Written = true
+		return r, nil
+	}
+
+	if string(recorded) != string(doc) {
+		r.Status = StatusFailed
+		r.Diff = diffLines(splitLines(string(recorded)), splitLines(string(doc)))
+	}
+
+	return r, nil
+}
+
+// document returns the normalized response as indented JSON
+func (s *Snapshot) document(res map[string]any) ([]byte, error) {
+	doc := map[string]any{}
+	if code, ok := res["code"]; ok {
+		doc["status"] = code
+	}
+
+	names := s.Headers
+	if len(names) == 0 {
+		names = snapshotHeaders
+	}
+	headers := map[string]any{}
+	for _, name := range names {
+		if v, ok := jsonChildFold(res["headers"], name); ok {
+			headers[strings.ToLower(name)] = v
+		}
+	}
+	if len(headers) > 0 {
+		doc["headers"] = headers
+	}
+	doc["body"] = res["body"]
+
+	normalized := normalizeJSON(doc)
+	for _, path := range s.Ignore {
+		if err := replaceJSONPath(normalized, path, snapshotIgnored); err != nil {
+			return nil, err
+		}
+	}
+
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(normalized); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+var snapshotNameRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// snapshotPath returns the path of the snapshot file of the step in the job,
+// which is next to the workflow file.
+func snapshotPath(workflowPath, job string, step int) string {
+	dir, file := filepath.Split(workflowPath)
+	base := strings.TrimSuffix(file, filepath.Ext(file))
+	return filepath.Join(dir, snapshotDir, base, fmt.Sprintf("%s-%d.json", snapshotName(job), step))
+}
+
+// snapshotName returns the id or the name of the job used in the file names
+func snapshotName(job string) string {
+	return strings.Trim(snapshotNameRe.ReplaceAllString(strings.ToLower(job), "-"), "-")
+}
+
+// validateSnapshots checks that jobs having snapshots don't share the files
+// by the ids or the names normalized to the same.
+func (w *Workflow) validateSnapshots() error {
+	e := &ValidationError{}
+	jobs := map[string]string{}
+
+	for _, job := range w.Jobs {
+		if !slices.ContainsFunc(job.Steps, func(st Step) bool { return st.Snapshot.Enabled() }) {
+			continue
+		}
+		key := cmp.Or(job.ID, job.Name)
+		name := snapshotName(key)
+		if other, exists := jobs[name]; exists {
+			e.AddMessage(fmt.Sprintf("snapshots of job '%s' and '%s' have the same files, set different ids", other, key))
+			continue
+		}
+		jobs[name] = key
+	}
+
+	if e.HasError() {
+		return e
+	}
+
+	return nil
+}
+
+func splitLines(s string) []string {
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines returns the removed lines from a with `-` and the added lines
+// of b with `+`, based on the longest common subsequence.
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for k := len(b) - 1; k >= 0; k-- {
+			if a[i] == b[k] {
+				lcs[i][k] = lcs[i+1][k+1] + 1
+			} else {
+				lcs[i][k] = max(lcs[i+1][k], lcs[i][k+1])
+			}
+		}
+	}
+
+	var diff []string
+	i, k := 0, 0
+	for i < len(a) && k < len(b) {
+		switch {
+		case a[i] == b[k]:
+			i++
+			k++
+		case lcs[i+1][k] >= lcs[i][k+1]:
+			diff = append(diff, "- "+strings.TrimSpace(a[i]))
+			i++
+		default:
+			diff = append(diff, "+ "+strings.TrimSpace(b[k]))
+			k++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, "- "+strings.TrimSpace(a[i]))
+	}
+	for ; k < len(b); k++ {
+		diff = append(diff, "+ "+strings.TrimSpace(b[k]))
+	}
+
+	return diff
+}
+
+func printSnapshot(w io.Writer, r *SnapshotResult, indent string) {
+	switch {
+	case r.Written:
+		fmt.Fprintf(w, "%s%s snapshot %s written\n", indent, color.BlueString("▲"), r.Path)
+	case r.Status == StatusFailed:
+		fmt.Fprintf(w, "%s%s snapshot %s\n", indent, color.RedString("✘"), r.Path)
+		for _, line := range r.Diff {
+			c := color.GreenString
+			if strings.HasPrefix(line, "-") {
+				c = color.RedString
+			}
+			fmt.Fprintf(w, "%s  %s\n", indent, c("%s", line))
+		}
+	default:
+		fmt.Fprintf(w, "%s%s snapshot %s\n", indent, color.GreenString("✔︎"), r.Path)
+	}
+}
+
+func (r *SnapshotResult) passed() bool {
+	return r == nil || r.Status != StatusFailed
+}
+
+func (r *SnapshotResult) message() string {
+	return fmt.Sprintf("snapshot %s does not match:\n%s", r.Path, strings.Join(r.Diff, "\n"))
+}
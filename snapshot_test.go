@@ -0,0 +1,153 @@
+package probe
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+)
+
+func TestSnapshotUnmarshalYAML(t *testing.T) {
+	tests := map[string]*Snapshot{
+		"snapshot: true":                      {enabled: true},
+		"snapshot: false":                     {enabled: false},
+		"snapshot:\n  ignore:\n  - $.body.id": {Ignore: []string{"$.body.id"}, enabled: true},
+	}
+
+	for src, expects := range tests {
+		var got struct {
+			Snapshot *Snapshot `yaml:"snapshot"`
+		}
+		if err := yaml.Unmarshal([]byte(src), &got); err != nil {
+			t.Fatalf("yaml.Unmarshal error %s", err)
+		}
+		if !reflect.DeepEqual(got.Snapshot, expects) {
+			t.Errorf("%s:\nExpected:\n%#v\nGot:\n%#v", src, expects, got.Snapshot)
+		}
+	}
+}
+
+func TestSnapshotCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), snapshotDir, "workflow", "job-0.json")
+	s := &Snapshot{Ignore: []string{"$.body.items[*].id", "$..token"}, enabled: true}
+	res := func(name string) map[string]any {
+		return map[string]any{
+			"code":    200,
+			"headers": map[string]string{"Content-Type": "application/json", "Date": "Mon, 01 Jan 2024"},
+			"body": map[string]any{
+				"name":  name,
+				"items": []any{map[string]any{"id": 1}, map[string]any{"id": 2}},
+				"auth":  map[string]any{"token": "xyz"},
+			},
+		}
+	}
+
+	// written at the first time
+	got, err := s.Check(path, res("alice"), false, nil)
+	if err != nil {
+		t.Fatalf("Check error %s", err)
+	}
+	if !got.Written || got.Status != StatusPassed {
+		t.Errorf("snapshot should be written: %#v", got)
+	}
+	b, _ := os.ReadFile(path)
+	for _, unexpected := range []string{"Date", `"id": 1`, "xyz"} {
+		if strings.Contains(string(b), unexpected) {
+			t.Errorf("snapshot should not contain %s:\n%s", unexpected, b)
+		}
+	}
+
+	// compared with the file
+	got, err = s.Check(path, res("alice"), false, nil)
+	if err != nil {
+		t.Fatalf("Check error %s", err)
+	}
+	if got.Written || got.Status != StatusPassed {
+		t.Errorf("snapshot should match: %#v", got)
+	}
+
+	got, err = s.Check(path, res("bob"), false, nil)
+	if err != nil {
+		t.Fatalf("Check error %s", err)
+	}
+	expects := []string{`- "name": "alice"`, `+ "name": "bob"`}
+	if got.Status != StatusFailed || !reflect.DeepEqual(got.Diff, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+
+	// rewritten with update
+	if _, err = s.Check(path, res("bob"), true, nil); err != nil {
+		t.Fatalf("Check error %s", err)
+	}
+	if got, _ = s.Check(path, res("bob"), false, nil); got.Status != StatusPassed {
+		t.Errorf("snapshot should be updated: %#v", got)
+	}
+}
+
+func TestSnapshotCheck_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), snapshotDir, "workflow", "job-0.json")
+	s := &Snapshot{enabled: true}
+	res := map[string]any{"code": 200, "body": map[string]any{"name": "alice"}}
+
+	// repeats of a job check the same file at once
+	var wg sync.WaitGroup
+	results := make([]*SnapshotResult, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = s.Check(path, res, false, nil)
+		}()
+	}
+	wg.Wait()
+
+	written := 0
+	for _, r := range results {
+		if r == nil || r.Status != StatusPassed {
+			t.Fatalf("expected all checks passed, got %#v", r)
+		}
+		if r.Written {
+			written++
+		}
+	}
+	if written != 1 {
+		t.Errorf("expected the file written once, got %d", written)
+	}
+}
+
+func TestWorkflowValidateSnapshots(t *testing.T) {
+	snap := &Snapshot{enabled: true}
+	w := &Workflow{Jobs: []Job{
+		{Name: "Users API", Steps: []Step{{Snapshot: snap}}},
+		{Name: "users api!", Steps: []Step{{Snapshot: snap}}},
+		{ID: "users-api-v2", Name: "Users API", Steps: []Step{{Snapshot: snap}}},
+		{Name: "Users API", Steps: []Step{{}}},
+	}}
+
+	err := w.validateSnapshots()
+	expects := "validation error:\nsnapshots of job 'Users API' and 'users api!' have the same files, set different ids"
+	if err == nil || err.Error() != expects {
+		t.Errorf("\nExpected:\n%s\nGot:\n%v", expects, err)
+	}
+}
+
+func TestSnapshotPath(t *testing.T) {
+	got := snapshotPath("checks/users.yml", "Users API #1", 2)
+	expects := filepath.Join("checks", snapshotDir, "users", "users-api-1-2.json")
+	if got != expects {
+		t.Errorf("\nExpected:\n%s\nGot:\n%s", expects, got)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	a := []string{"{", `"a": 1,`, `"b": 2`, "}"}
+	b := []string{"{", `"a": 1,`, `"b": 3,`, `"c": 4`, "}"}
+	expects := []string{`- "b": 2`, `+ "b": 3,`, `+ "c": 4`}
+	if got := diffLines(a, b); !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+}
@@ -0,0 +1,179 @@
+package probe
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/fatih/color"
+)
+
+// Suite runs the workflows found in files, directories and globs,
+// and aggregates the results into one report.
+type Suite struct {
+	Patterns []string
+	Parallel int
+	Filter   Filter
+	Env      map[string]string
+	Vars     map[string]any
+	Secrets  map[string]any
+	// UpdateSnapshots rewrites the snapshot files with the responses
+	UpdateSnapshots bool
+	// Registry runs the registered actions in the process
+	Registry *Registry
+	config   Config
+}
+
+func NewSuite(patterns []string, v bool) *Suite {
+	return &Suite{
+		Patterns: patterns,
+		Parallel: 1,
+		config: Config{
+			Log:     os.Stdout,
+			Verbose: v,
+		},
+	}
+}
+
+func (s *Suite) Do() (*Report, error) {
+	paths, err := FindWorkflows(s.Patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	results := make([]*WorkflowResult, len(paths))
+	header := len(paths) > 1
+
+	if s.Parallel <= 1 {
+		for i, path := range paths {
+			results[i] = s.run(path, s.config.Log, header)
+		}
+	} else {
+		// outputs are buffered and flushed in order of paths
+		bufs := make([]bytes.Buffer, len(paths))
+		done := make([]chan struct{}, len(paths))
+		sem := make(chan struct{}, s.Parallel)
+		var wg sync.WaitGroup
+
+		for i, path := range paths {
+			done[i] = make(chan struct{})
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer close(done[i])
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				results[i] = s.run(path, &bufs[i], header)
+			}()
+		}
+
+		for i := range paths {
+			<-done[i]
+			if _, err := io.Copy(s.config.Log, &bufs[i]); err != nil {
+				return nil, err
+			}
+		}
+		wg.Wait()
+	}
+
+	report := &Report{}
+	for _, r := range results {
+		report.Add(r)
+	}
+	report.Duration = time.Since(started)
+
+	return report, nil
+}
+
+func (s *Suite) run(path string, w io.Writer, header bool) *WorkflowResult {
+	if header {
+		fmt.Fprintf(w, "%s\n", color.HiBlackString("# %s", path))
+	}
+
+	c := s.config
+	c.Log = w
+	c.Filter = s.Filter
+	c.Env = s.Env
+	c.Vars = s.Vars
+	c.Secrets = s.Secrets
+	c.UpdateSnapshots = s.UpdateSnapshots
+	c.Registry = s.Registry
+	p := &Probe{FilePath: path, config: c}
+	if err := p.Do(); err != nil {
+		fmt.Fprintf(w, "%s\n", err)
+	}
+
+	return p.Result()
+}
+
+// FindWorkflows returns the workflow paths matched by patterns.
+// A pattern is a file, a directory searched recursively for yaml files,
+// or a glob that supports `**`.
+func FindWorkflows(patterns []string) ([]string, error) {
+	var paths []string
+	seen := map[string]bool{}
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	for _, pattern := range patterns {
+		if info, err := os.Stat(pattern); err == nil {
+			if !info.IsDir() {
+				add(pattern)
+				continue
+			}
+			err := filepath.WalkDir(pattern, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() {
+					if path != pattern && strings.HasPrefix(d.Name(), ".") {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if isWorkflowFile(path) {
+					add(path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		matches, err := doublestar.FilepathGlob(pattern, doublestar.WithFilesOnly())
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no workflow matches %s", pattern)
+		}
+		for _, m := range matches {
+			add(m)
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no workflow found")
+	}
+
+	return paths, nil
+}
+
+func isWorkflowFile(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".yml" || ext == ".yaml"
+}
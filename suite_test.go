@@ -0,0 +1,130 @@
+package probe
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFindWorkflows(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		expects  []string
+	}{
+		{
+			patterns: []string{"testdata/suite"},
+			expects:  []string{"testdata/suite/a.yml", "testdata/suite/nested/b.yaml"},
+		},
+		{
+			patterns: []string{"testdata/suite/**/*.yaml", "testdata/suite/a.yml"},
+			expects:  []string{"testdata/suite/nested/b.yaml", "testdata/suite/a.yml"},
+		},
+		{
+			patterns: []string{"testdata/suite/a.yml", "testdata/suite"},
+			expects:  []string{"testdata/suite/a.yml", "testdata/suite/nested/b.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := FindWorkflows(tt.patterns)
+		if err != nil {
+			t.Fatalf("FindWorkflows error %s", err)
+		}
+		if !reflect.DeepEqual(got, tt.expects) {
+			t.Errorf("\nExpected:\n%#v\nGot:\n%#v", tt.expects, got)
+		}
+	}
+}
+
+func TestFindWorkflows_NoMatch(t *testing.T) {
+	if _, err := FindWorkflows([]string{"testdata/suite/**/*.json"}); err == nil {
+		t.Error("expected error, but got nil")
+	}
+}
+
+func TestSuiteDo(t *testing.T) {
+	s := NewSuite([]string{"testdata/unknown-field.yml", "testdata/missing-uses.yml"}, false)
+	s.Parallel = 2
+	var buf bytes.Buffer
+	s.config.Log = &buf
+
+	report, err := s.Do()
+	if err != nil {
+		t.Fatalf("Suite.Do error %s", err)
+	}
+	if len(report.Workflows) != 2 {
+		t.Fatalf("expected 2 workflows, but got %d", len(report.Workflows))
+	}
+	if report.Workflows[0].Path != "testdata/unknown-field.yml" {
+		t.Errorf("results are not in order of paths: %s", report.Workflows[0].Path)
+	}
+	if report.Count(StatusFailed) != 2 || report.ExitStatus() != 1 {
+		t.Errorf("expected all workflows failed, but got %#v", report)
+	}
+}
+
+// barrierActions returns when all of the jobs have called it, so the jobs
+// write their outputs at the same time.
+type barrierActions struct {
+	wg *sync.WaitGroup
+}
+
+func (a barrierActions) Run(args []string, with map[string]any) (map[string]any, error) {
+	a.wg.Done()
+	a.wg.Wait()
+	return map[string]any{"res": map[string]any{"msg": with["msg"]}}, nil
+}
+
+func TestSuiteDo_ParallelJobs(t *testing.T) {
+	dir := t.TempDir()
+	wf := `name: parallel
+jobs:
+- name: First
+  steps:
+  - uses: barrier
+    with:
+      msg: first
+    test: res.msg == "first"
+- name: Second
+  steps:
+  - uses: barrier
+    with:
+      msg: second
+    test: res.msg == "second"
+`
+	var paths []string
+	for _, name := range []string{"a.yml", "b.yml"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(wf), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	// 2 jobs of 2 workflows
+	var wg sync.WaitGroup
+	wg.Add(4)
+	r := NewRegistry()
+	r.Register("barrier", barrierActions{wg: &wg})
+
+	s := NewSuite(paths, true)
+	s.Parallel = 2
+	s.Registry = r
+	var buf bytes.Buffer
+	s.config.Log = &buf
+
+	report, err := s.Do()
+	if err != nil {
+		t.Fatalf("Suite.Do error %s", err)
+	}
+	if report.ExitStatus() != 0 {
+		t.Errorf("expected all workflows passed, got\n%s", buf.String())
+	}
+	if n := strings.Count(buf.String(), "First\n"); n != 2 {
+		t.Errorf("expected the output of the job of 2 workflows, got %d:\n%s", n, buf.String())
+	}
+}
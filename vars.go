@@ -0,0 +1,62 @@
+package probe
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/joho/godotenv"
+)
+
+// LoadEnvFiles reads dotenv files and merges them in order,
+// so the later file overrides the earlier.
+func LoadEnvFiles(paths ...string) (map[string]string, error) {
+	env := map[string]string{}
+
+	for _, path := range paths {
+		m, err := godotenv.Read(path)
+		if err != nil {
+			return nil, fmt.Errorf("env file %s: %w", path, err)
+		}
+		maps.Copy(env, m)
+	}
+
+	return env, nil
+}
+
+// LoadVarsFiles reads yaml files of variables and merges them in order,
+// so the later file overrides the earlier.
+func LoadVarsFiles(paths ...string) (map[string]any, error) {
+	vars := map[string]any{}
+
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		m := map[string]any{}
+		if err := yaml.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("vars file %s: %w", path, err)
+		}
+		maps.Copy(vars, m)
+	}
+
+	return vars, nil
+}
+
+// ParseVars parses key=value pairs
+func ParseVars(pairs []string) (map[string]any, error) {
+	vars := map[string]any{}
+
+	for _, pair := range pairs {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("var must be key=value: %s", pair)
+		}
+		vars[k] = v
+	}
+
+	return vars, nil
+}
@@ -0,0 +1,62 @@
+package probe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadEnvFiles(t *testing.T) {
+	got, err := LoadEnvFiles("testdata/vars/staging.env", "testdata/vars/production.env")
+	if err != nil {
+		t.Fatalf("LoadEnvFiles error %s", err)
+	}
+
+	expects := map[string]string{"TARGET": "production", "TOKEN": "abc def"}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+}
+
+func TestLoadVarsFiles(t *testing.T) {
+	got, err := LoadVarsFiles("testdata/vars/vars.yml")
+	if err != nil {
+		t.Fatalf("LoadVarsFiles error %s", err)
+	}
+
+	expects := map[string]any{"region": "ap", "port": uint64(8080)}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+}
+
+func TestParseVars(t *testing.T) {
+	got, err := ParseVars([]string{"a=1", "b=x=y"})
+	if err != nil {
+		t.Fatalf("ParseVars error %s", err)
+	}
+
+	expects := map[string]any{"a": "1", "b": "x=y"}
+	if !reflect.DeepEqual(got, expects) {
+		t.Errorf("\nExpected:\n%#v\nGot:\n%#v", expects, got)
+	}
+
+	if _, err := ParseVars([]string{"novalue"}); err == nil {
+		t.Error("expected error, but got nil")
+	}
+}
+
+func TestCreateContextPrecedence(t *testing.T) {
+	t.Setenv("PROBE_TEST_TARGET", "shell")
+	w := &Workflow{}
+	ctx := w.createContext(Config{
+		Env:  map[string]string{"PROBE_TEST_TARGET": "file"},
+		Vars: map[string]any{"a": "1"},
+	})
+
+	if ctx.Envs["PROBE_TEST_TARGET"] != "file" {
+		t.Errorf("env file should override the process env: %s", ctx.Envs["PROBE_TEST_TARGET"])
+	}
+	if ctx.Vars["a"] != "1" {
+		t.Errorf("vars are not set: %#v", ctx.Vars)
+	}
+}
@@ -1,7 +1,14 @@
 package probe
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -9,9 +16,16 @@ import (
 )
 
 type Workflow struct {
-	Name       string `yaml:"name",validate:"required"`
-	Jobs       []Job  `yaml:"jobs",validate:"required"`
+	Name string `yaml:"name" validate:"required"`
+	Jobs []Job  `yaml:"jobs" validate:"required"`
+	// Secrets are names of env or vars whose values are masked in outputs
+	Secrets []string `yaml:"secrets"`
+	// Delimiters are the start and the end of expressions in templates
+	Delimiters []string `yaml:"delimiters" validate:"omitempty,len=2"`
+	// Actions pin the executables of external actions by name
+	Actions    map[string]ActionSpec `yaml:"actions" validate:"dive"`
 	exitStatus int
+	path       string
 }
 
 func (w *Workflow) SetExitStatus(isErr bool) {
@@ -20,48 +34,302 @@ func (w *Workflow) SetExitStatus(isErr bool) {
 	}
 }
 
-func (w *Workflow) Start(c Config) {
+func (w *Workflow) Start(c Config) []*JobResult {
 	ctx := w.createContext(c)
+	defer ctx.Config.Log.(*lockedWriter).Flush()
+	w.applyFilter(c.Filter)
+
+	// plugin processes are shared by the jobs and shut down at the end
+	ctx.actions = NewActionPool(ctx.Config.Log, ctx.Config.Verbose)
+	ctx.actions.specs = w.Actions
+	ctx.actions.dir = filepath.Dir(w.path)
+	ctx.actions.registry = c.Registry
+	defer ctx.actions.Close()
+
 	var wg sync.WaitGroup
 
+	// results are stored in order of jobs and repeats
+	results := make([][]*JobResult, len(w.Jobs))
+
+	// closed when all runs of the job identified by id are finished
+	done := map[string]chan struct{}{}
+	failed := map[string]*bool{}
+	owners := map[string]int{}
+	for i, job := range w.Jobs {
+		if _, exists := done[job.ID]; job.ID != "" && !exists {
+			done[job.ID] = make(chan struct{})
+			failed[job.ID] = new(bool)
+			owners[job.ID] = i
+		}
+	}
+
+	for i, job := range w.Jobs {
+		runs := 1
+		if job.Repeat != nil {
+			runs = job.Repeat.Count
+		}
+		results[i] = make([]*JobResult, runs)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			owner := job.ID != "" && owners[job.ID] == i
+			if owner {
+				defer close(done[job.ID])
+			}
+
+			// jobs run after the jobs they need, and are skipped when any of
+			// them failed
+			for _, id := range job.Needs {
+				ch, ok := done[id]
+				if !ok {
+					continue
+				}
+				<-ch
+				if *failed[id] && !job.skipped {
+					job.skipped = true
+					job.skipReason = fmt.Sprintf("needs %s failed", id)
+				}
+			}
+
+			if job.skipped {
+				fmt.Fprintf(ctx.Config.Log, "%s\n", color.HiBlackString("%s (skipped: %s)", job.Name, job.skipReason))
+				for k := range results[i] {
+					results[i][k] = job.skippedResult()
+				}
+				return
+			}
+
+			var jwg sync.WaitGroup
+			for k := 0; k < runs; k++ {
+				jwg.Add(1)
+				go func() {
+					defer jwg.Done()
+					// each repeat runs with its own copy of the job
+					j := job
+					results[i][k] = j.Start(ctx)
+				}()
+				if job.Repeat != nil {
+					select {
+					case <-time.After(time.Duration(job.Repeat.Interval) * time.Second):
+					case <-ctx.context().Done():
+					}
+				}
+			}
+			jwg.Wait()
+
+			if owner {
+				for _, r := range results[i] {
+					if r.Status == StatusFailed {
+						*failed[job.ID] = true
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	var flat []*JobResult
+	for _, rs := range results {
+		for _, r := range rs {
+			w.SetExitStatus(r.Status == StatusFailed)
+			flat = append(flat, r)
+		}
+	}
+
+	return flat
+}
+
+// applyFilter marks jobs and steps not selected by the filter as skipped.
+// Jobs needed by the selected jobs are kept with all steps except the ones
+// of SkipTags.
+func (w *Workflow) applyFilter(f Filter) {
+	ids := map[string]int{}
+	selected := make([]bool, len(w.Jobs))
+
+	for i := range w.Jobs {
+		job := &w.Jobs[i]
+		if job.ID != "" {
+			ids[job.ID] = i
+		}
+		job.skipSteps = make([]bool, len(job.Steps))
+		matched := false
+		for k, st := range job.Steps {
+			tags := append(append([]string{}, job.Tags...), st.Tags...)
+			if f.MatchTags(tags) {
+				matched = true
+			} else {
+				job.skipSteps[k] = true
+			}
+		}
+		selected[i] = matched && f.MatchJob(job.Name)
+	}
+
+	var keep func(i int)
+	keep = func(i int) {
+		for _, id := range w.Jobs[i].Needs {
+			n, ok := ids[id]
+			if !ok || selected[n] {
+				continue
+			}
+			selected[n] = true
+			job := &w.Jobs[n]
+			for k, st := range job.Steps {
+				job.skipSteps[k] = f.SkipsTags(append(append([]string{}, job.Tags...), st.Tags...))
+			}
+			keep(n)
+		}
+	}
+	for i := range w.Jobs {
+		if selected[i] {
+			keep(i)
+		}
+	}
+
+	for i := range w.Jobs {
+		if !selected[i] {
+			w.Jobs[i].skipped = true
+			w.Jobs[i].skipReason = "filtered"
+		}
+	}
+}
+
+// validateNeeds checks that needs refer to existing jobs without cycles
+func (w *Workflow) validateNeeds() error {
+	e := &ValidationError{}
+	needs := map[string][]string{}
+
 	for _, job := range w.Jobs {
-		// No repeat
-		if job.Repeat == nil {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				w.SetExitStatus(job.Start(ctx))
-			}()
+		if job.ID == "" {
 			continue
 		}
+		if _, exists := needs[job.ID]; exists {
+			e.AddMessage(fmt.Sprintf("job id '%s' is duplicated", job.ID))
+		}
+		needs[job.ID] = job.Needs
+	}
 
-		// Repeat
-		for i := 0; i < job.Repeat.Count; i++ {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				w.SetExitStatus(job.Start(ctx))
-			}()
-			time.Sleep(time.Duration(job.Repeat.Interval) * time.Second)
+	for _, job := range w.Jobs {
+		for _, id := range job.Needs {
+			if _, exists := needs[id]; !exists {
+				e.AddMessage(fmt.Sprintf("job '%s' needs unknown job id '%s'", job.Name, id))
+			}
 		}
 	}
 
-	wg.Wait()
+	// depth-first search for cycles
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var visit func(id string) bool
+	visit = func(id string) bool {
+		switch state[id] {
+		case visiting:
+			return false
+		case visited:
+			return true
+		}
+		state[id] = visiting
+		for _, n := range needs[id] {
+			if !visit(n) {
+				return false
+			}
+		}
+		state[id] = visited
+		return true
+	}
+	for _, job := range w.Jobs {
+		if job.ID != "" && !visit(job.ID) {
+			e.AddMessage(fmt.Sprintf("needs of job id '%s' have a cycle", job.ID))
+			break
+		}
+	}
+
+	if e.HasError() {
+		return e
+	}
+
+	return nil
+}
+
+func (w *Workflow) newExpr() *Expr {
+	if len(w.Delimiters) == 2 {
+		return NewExprWithDelims(w.Delimiters[0], w.Delimiters[1])
+	}
+	return NewExpr()
 }
 
 func (w *Workflow) createContext(c Config) JobContext {
+	if c.Log == nil {
+		c.Log = os.Stdout
+	}
+	envs := getEnvMap()
+	maps.Copy(envs, c.Env)
+	vars := map[string]any{}
+	maps.Copy(vars, c.Vars)
+
+	secrets := map[string]any{}
+	maps.Copy(secrets, c.Secrets)
+
+	values := append(secretValues(w.Secrets, envs, vars), stringValues(secrets)...)
+	masker := NewMasker(values)
+	// jobs and repeats write to the log concurrently
+	c.Log = &lockedWriter{w: masker.Writer(c.Log)}
+
 	return JobContext{
-		Envs:   getEnvMap(),
-		Logs:   []map[string]any{},
-		Config: c,
+		Envs:       envs,
+		Vars:       vars,
+		Secrets:    secrets,
+		Logs:       []map[string]any{},
+		Config:     c,
+		masker:     masker,
+		expr:       w.newExpr(),
+		path:       w.path,
+		allOutputs: newSharedOutputs(),
+	}
+}
+
+// lockedWriter serializes writes to w
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.w.Write(p)
+}
+
+// Flush flushes w buffering lines such as the writer of Masker
+func (lw *lockedWriter) Flush() error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	if f, ok := lw.w.(interface{ Flush() error }); ok {
+		return f.Flush()
 	}
+	return nil
 }
 
 type JobContext struct {
-	Envs map[string]string `expr:"env"`
-	Logs []map[string]any  `expr:"steps"`
+	Envs    map[string]string `expr:"env"`
+	Vars    map[string]any    `expr:"vars"`
+	Secrets map[string]any    `expr:"secrets"`
+	Logs    []map[string]any  `expr:"steps"`
+	Outputs map[string]any    `expr:"outputs"`
 	Config
 	Failed bool
+	masker *Masker
+	expr   *Expr
+	// path is the path of the workflow file
+	path string
+	// actions is the pool of the plugin processes of actions
+	actions *ActionPool
+	// outputs are the outputs of all jobs
+	allOutputs *sharedOutputs
 }
 
 func (j *JobContext) SetFailed() {
@@ -69,53 +337,116 @@ func (j *JobContext) SetFailed() {
 }
 
 type TestContext struct {
-	Envs map[string]string `expr:"env"`
-	Logs []map[string]any  `expr:"steps"`
-	Res  map[string]any    `expr:"res"`
-	Req  map[string]any    `expr:"req"`
+	Envs    map[string]string `expr:"env"`
+	Vars    map[string]any    `expr:"vars"`
+	Secrets map[string]any    `expr:"secrets"`
+	Logs    []map[string]any  `expr:"steps"`
+	Outputs map[string]any    `expr:"outputs"`
+	Res     map[string]any    `expr:"res"`
+	Req     map[string]any    `expr:"req"`
 }
 
 type Repeat struct {
-	Count    int `yaml:"count",validate:"required,gte=0,lt=100"`
-	Interval int `yaml:"interval,validate:"gte=0,lt=600"`
+	Count    int `yaml:"count" validate:"required,gte=0,lt=100"`
+	Interval int `yaml:"interval" validate:"gte=0,lt=600"`
 }
 
 type Step struct {
-	Name string         `yaml:"name"`
-	Uses string         `yaml:"uses" validate:"required"`
-	With map[string]any `yaml:"with"`
-	Test string         `yaml:"test"`
-	Echo string         `yaml:"echo"`
-	log  map[string]any
-	err  error
+	Name     string         `yaml:"name"`
+	Uses     string         `yaml:"uses" validate:"required"`
+	With     map[string]any `yaml:"with"`
+	Test     string         `yaml:"test"`
+	Expect   Expectations   `yaml:"expect"`
+	Schema   any            `yaml:"schema"`
+	Snapshot *Snapshot      `yaml:"snapshot"`
+	Outputs  Outputs        `yaml:"outputs"`
+	Echo     string         `yaml:"echo"`
+	Tags     []string       `yaml:"tags"`
+	log      map[string]any
+	err      error
 }
 
 type Job struct {
-	Name     string  `yaml:"name",validate:"required"`
-	Steps    []Step  `yaml:"steps",validate:"required"`
-	Repeat   *Repeat `yaml:"repeat"`
-	Defaults any     `yaml:"defaults"`
-	ctx      *JobContext
+	ID         string   `yaml:"id"`
+	Name       string   `yaml:"name"`
+	Needs      []string `yaml:"needs"`
+	Tags       []string `yaml:"tags"`
+	Steps      []Step   `yaml:"steps" validate:"required"`
+	Repeat     *Repeat  `yaml:"repeat"`
+	Defaults   any      `yaml:"defaults"`
+	ctx        *JobContext
+	skipped    bool
+	skipReason string
+	skipSteps  []bool
 }
 
-func (j *Job) Start(ctx JobContext) bool {
+func (j *Job) Start(ctx JobContext) *JobResult {
 	j.ctx = &ctx
 	if j.Name == "" {
 		j.Name = "Unknown Job"
 	}
-	fmt.Printf("%s\n", j.Name)
+	w := ctx.Config.Log
+	fmt.Fprintf(w, "%s\n", j.Name)
+
+	result := &JobResult{Name: j.Name}
+	started := time.Now()
+	defer func() {
+		result.Duration = time.Since(started)
+		result.Status = StatusPassed
+		if j.ctx.Failed {
+			result.Status = StatusFailed
+		}
+		j.ctx.masker.MaskJobResult(result)
+	}()
 
-	expr := NewExpr()
+	expr := ctx.expr
+	if expr == nil {
+		expr = NewExpr()
+	}
+
+	// outputs of this run take precedence over the ones of other jobs
+	own := map[string]any{}
 
 	for i, st := range j.Steps {
 		if st.Name == "" {
 			st.Name = "Unknown Step"
 		}
+		ctx.Outputs = ctx.allOutputs.with(own)
+
+		if j.skipSteps != nil && j.skipSteps[i] {
+			result.Steps = append(result.Steps, &StepResult{Index: i, Name: st.Name, Uses: st.Uses, Test: st.Test, Status: StatusSkipped})
+			// keep the index of steps
+			ctx.Logs = append(ctx.Logs, map[string]any{})
+			fmt.Fprintf(w, "%s\n", color.HiBlackString("%2d. - %s (skipped)", i, st.Name))
+			continue
+		}
+
+		sr := &StepResult{Index: i, Name: st.Name, Uses: st.Uses, Test: st.Test, Status: StatusPassed}
+		result.Steps = append(result.Steps, sr)
+		if err := ctx.context().Err(); err != nil {
+			st.err = err
+			ctx.Logs = append(ctx.Logs, map[string]any{})
+			j.stepError(w, i, st.Name, sr, err)
+			continue
+		}
+		stepStarted := time.Now()
 
-		expW := expr.EvalTemplate(st.With, ctx)
-		ret, err := RunActions(st.Uses, []string{}, expW, j.ctx.Config.Verbose)
+		var ret map[string]any
+		expW, err := expr.EvalTemplate(st.With, ctx)
+		if err != nil {
+			err = fmt.Errorf("with.%w", err)
+		} else {
+			ret, err = j.ctx.actions.RunStream(ctx.context(), st.Uses, []string{}, expW, func(p Progress) {
+				sr.Progress = append(sr.Progress, p)
+				fmt.Fprintf(w, "%s\n", color.HiBlackString("%2d. ⋯  %s", i, p))
+			})
+		}
 		if err != nil {
 			st.err = err
+			// keep the index of steps
+			ctx.Logs = append(ctx.Logs, map[string]any{})
+			j.stepError(w, i, st.Name, sr, err)
+			sr.Duration = time.Since(stepStarted)
 			continue
 		}
 
@@ -129,56 +460,115 @@ func (j *Job) Start(ctx JobContext) bool {
 				res["body"] = mustMarshalJSON(body)
 			}
 		}
+		sr.Req = req
+		sr.Res = res
 
 		// set log and logs
 		st.log = ret
 		ctx.Logs = append(ctx.Logs, st.log)
 
+		var violations []SchemaViolation
+		if st.Schema != nil && okres {
+			violations, err = ValidateSchema(st.Schema, filepath.Dir(ctx.path), res["body"])
+			if err != nil {
+				j.stepError(w, i, st.Name, sr, err)
+				sr.Duration = time.Since(stepStarted)
+				continue
+			}
+			res["schema"] = schemaResult(violations)
+		}
+
+		if st.Snapshot.Enabled() && okres {
+			path := snapshotPath(ctx.path, cmp.Or(j.ID, j.Name), i)
+			sr.Snapshot, err = st.Snapshot.Check(path, res, j.ctx.Config.UpdateSnapshots, j.ctx.masker)
+			if err != nil {
+				j.stepError(w, i, st.Name, sr, err)
+				sr.Duration = time.Since(stepStarted)
+				continue
+			}
+		}
+
 		output := ""
 
 		if j.ctx.Config.Verbose && okreq && okres {
-			showVerbose(i, st.Name, req, res)
-			if st.Test == "" {
+			showVerbose(w, i, st.Name, req, res)
+			env := NewTestContext(ctx, req, res)
+			if st.Test == "" && !st.hasChecks() {
+				outs, err := extractOutputs(st, env)
+				j.setOutputs(sr, outs, err, own)
+				printOutputs(w, sr.Outputs, err)
+				sr.Duration = time.Since(stepStarted)
 				continue
 			}
 
 			input := st.Test
-			env := NewTestContext(ctx, req, res)
 
-			exprOut, err := EvalExpr(input, env)
-			if err != nil {
-				fmt.Printf("%s: %#v (input: %s)\n", color.RedString("Test Error"), err, input)
-				j.ctx.SetFailed()
-			} else {
-				boolOutput, boolOk := exprOut.(bool)
-				if boolOk {
-					boolResultStr := color.GreenString("Success")
-					if !boolOutput {
-						boolResultStr = color.RedString("Failure")
+			if input != "" {
+				exprOut, err := EvalExpr(input, env)
+				if err != nil {
+					fmt.Fprintf(w, "%s: %#v (input: %s)\n", color.RedString("Test Error"), err, input)
+					sr.fail(err.Error())
+					j.ctx.SetFailed()
+				} else {
+					boolOutput, boolOk := exprOut.(bool)
+					if boolOk {
+						boolResultStr := color.GreenString("Success")
+						if !boolOutput {
+							boolResultStr = color.RedString("Failure")
+							sr.Diagnosis = Diagnose(input, env)
+							sr.fail(diagnosisMessage(sr.Diagnosis))
+							j.ctx.SetFailed()
+						}
+						fmt.Fprintf(w, "Test: %s (input: %s, env: %#v)\n", boolResultStr, input, env)
+						printDiagnosis(w, sr.Diagnosis, "  ")
+					} else {
+						fmt.Fprintf(w, "Test: `%s` = %s\n", st.Test, exprOut)
+						sr.fail(fmt.Sprintf("test returned non-boolean: %v", exprOut))
 						j.ctx.SetFailed()
 					}
-					fmt.Printf("Test: %s (input: %s, env: %#v)\n", boolResultStr, input, env)
-				} else {
-					fmt.Printf("Test: `%s` = %s\n", st.Test, exprOut)
-					j.ctx.SetFailed()
 				}
 			}
 
+			if len(violations) > 0 {
+				fmt.Fprintln(w, "Schema:")
+				printViolations(w, violations, "  ")
+			}
+
+			if sr.Snapshot != nil {
+				fmt.Fprintln(w, "Snapshot:")
+				printSnapshot(w, sr.Snapshot, "  ")
+			}
+
+			if len(st.Expect) > 0 {
+				sr.Expects = st.Expect.Eval(env)
+				fmt.Fprintln(w, "Expect:")
+				printExpects(w, sr.Expects, "  ")
+			}
+			j.failChecks(sr, violations)
+
+			// outputs are extracted after the checks, so a failed extraction
+			// doesn't hide their results
+			outs, err := extractOutputs(st, env)
+			j.setOutputs(sr, outs, err, own)
+			printOutputs(w, sr.Outputs, err)
+
 			// Echo
 			if st.Echo != "" {
 				exprOut, err := EvalExpr(st.Echo, NewTestContext(ctx, req, res))
 				if err != nil {
-					fmt.Printf("%s: %#v (input: %s)\n", color.RedString("Echo Error"), err, st.Echo)
+					fmt.Fprintf(w, "%s: %#v (input: %s)\n", color.RedString("Echo Error"), err, st.Echo)
 				} else {
-					fmt.Printf("Echo: %s\n", exprOut)
+					sr.Echo = fmt.Sprintf("%v", exprOut)
+					fmt.Fprintf(w, "Echo: %s\n", exprOut)
 				}
 			}
 
-			fmt.Println("- - -")
+			fmt.Fprintln(w, "- - -")
+			sr.Duration = time.Since(stepStarted)
 			continue
 
 		} else if j.ctx.Config.Verbose {
-			fmt.Print("sorry, request or response is nil")
+			fmt.Fprint(w, "sorry, request or response is nil")
 		}
 
 		// Output format here:
@@ -186,87 +576,238 @@ func (j *Job) Start(ctx JobContext) bool {
 		num := color.HiBlackString(fmt.Sprintf("%2d.", i))
 		output = fmt.Sprintf("%s %%s %s", num, st.Name)
 
+		env := NewTestContext(ctx, req, res)
+		if len(st.Expect) > 0 {
+			sr.Expects = st.Expect.Eval(env)
+		}
+		var testOut any
+		var testErr error
 		if st.Test != "" {
-			exprOut, err := EvalExpr(st.Test, NewTestContext(ctx, req, res))
-			if err != nil {
+			testOut, testErr = EvalExpr(st.Test, env)
+		}
+
+		// outputs are extracted after the checks, so a failed extraction
+		// doesn't hide their results
+		outs, outErr := extractOutputs(st, env)
+		checksOK := passedExpects(sr.Expects) && len(violations) == 0 && sr.Snapshot.passed() && outErr == nil
+
+		if st.Test != "" {
+			if testErr != nil {
 				output = fmt.Sprintf(output+"\n", "-")
-				output += fmt.Sprintf("Test\nerror: %#v\n", err)
+				output += fmt.Sprintf("Test\nerror: %#v\n", testErr)
+				sr.fail(testErr.Error())
 				j.ctx.SetFailed()
 			} else {
-				boolOutput, boolOk := exprOut.(bool)
+				boolOutput, boolOk := testOut.(bool)
 				if boolOk {
 					boolResultStr := color.GreenString("✔︎ ")
 					if !boolOutput {
 						boolResultStr = color.RedString("✘ ")
+						sr.Diagnosis = Diagnose(st.Test, env)
+						sr.fail(diagnosisMessage(sr.Diagnosis))
 						j.ctx.SetFailed()
+					} else if !checksOK {
+						boolResultStr = color.RedString("✘ ")
 					}
 					output = fmt.Sprintf(output+"\n", boolResultStr)
 					if !boolOutput {
+						var b strings.Builder
 						// 7 spaces
+						printDiagnosis(&b, sr.Diagnosis, "       ")
+						output += b.String()
 						output += fmt.Sprintf("       request: %#v\n", req)
 						output += fmt.Sprintf("       response: %#v\n", res)
 					}
 				} else {
 					output = fmt.Sprintf(output+"\n", "-")
-					output += fmt.Sprintf("Test: `%s` = %s\n", st.Test, exprOut)
+					output += fmt.Sprintf("Test: `%s` = %s\n", st.Test, testOut)
+					sr.fail(fmt.Sprintf("test returned non-boolean: %v", testOut))
 					j.ctx.SetFailed()
 				}
 			}
+		} else if st.hasChecks() || outErr != nil {
+			mark := color.GreenString("✔︎ ")
+			if !checksOK {
+				mark = color.RedString("✘ ")
+			}
+			output = fmt.Sprintf(output+"\n", mark)
 		} else {
 			output = fmt.Sprintf(output+"\n", color.BlueString("▲ "))
 		}
 
-		fmt.Print(output)
+		fmt.Fprint(w, output)
+
+		// 7 spaces
+		printViolations(w, violations, "       ")
+		if sr.Snapshot != nil {
+			printSnapshot(w, sr.Snapshot, "       ")
+		}
+		printExpects(w, sr.Expects, "       ")
+		j.failChecks(sr, violations)
+		j.setOutputs(sr, outs, outErr, own)
+		if outErr != nil {
+			// 7 spaces
+			fmt.Fprintf(w, "       error: %s\n", outErr)
+		}
 
 		// Echo
 		if st.Echo != "" {
 			exprOut, err := EvalExpr(st.Echo, NewTestContext(ctx, req, res))
 			if err != nil {
-				fmt.Printf("Echo\nerror: %#v\n", err)
+				fmt.Fprintf(w, "Echo\nerror: %#v\n", err)
 			} else {
+				sr.Echo = fmt.Sprintf("%v", exprOut)
 				// 7 spaces
-				fmt.Printf("       %s\n", exprOut)
+				fmt.Fprintf(w, "       %s\n", exprOut)
 			}
 		}
+
+		sr.Duration = time.Since(stepStarted)
 	}
 
-	return j.ctx.Failed
+	return result
+}
+
+// failChecks fails the step when the body violates the schema, differs from
+// the snapshot, or some of the expectations failed
+func (j *Job) failChecks(sr *StepResult, violations []SchemaViolation) {
+	if len(violations) == 0 && sr.Snapshot.passed() && passedExpects(sr.Expects) {
+		return
+	}
+
+	var msgs []string
+	if sr.Error != "" {
+		msgs = append(msgs, sr.Error)
+	}
+	if len(violations) > 0 {
+		msgs = append(msgs, schemaMessage(violations))
+	}
+	if !sr.Snapshot.passed() {
+		msgs = append(msgs, sr.Snapshot.message())
+	}
+	for _, e := range sr.Expects {
+		if e.Status == StatusFailed {
+			msgs = append(msgs, e.message())
+		}
+	}
+
+	sr.fail(strings.Join(msgs, "\n"))
+	j.ctx.SetFailed()
+}
+
+// extractOutputs extracts the outputs of the step without adding them to
+// the outputs of env, which are added by setOutputs after the checks.
+func extractOutputs(st Step, env TestContext) (map[string]any, error) {
+	if len(st.Outputs) == 0 {
+		return nil, nil
+	}
+	env.Outputs = maps.Clone(env.Outputs)
+	return st.Outputs.Eval(env)
+}
+
+// setOutputs sets the outputs to the step and adds them to the outputs of
+// the following steps and jobs, or fails the step by the error.
+func (j *Job) setOutputs(sr *StepResult, outs map[string]any, err error, own map[string]any) {
+	if err != nil {
+		msg := err.Error()
+		if sr.Error != "" {
+			msg = sr.Error + "\n" + msg
+		}
+		sr.fail(msg)
+		j.ctx.SetFailed()
+		return
+	}
+	if outs == nil {
+		return
+	}
+	sr.Outputs = outs
+	maps.Copy(own, outs)
+	// echo of the step shows them as well
+	maps.Copy(j.ctx.Outputs, outs)
+	j.ctx.allOutputs.add(outs)
+}
+
+// printOutputs prints the outputs of the step in verbose
+func printOutputs(w io.Writer, outs map[string]any, err error) {
+	if err != nil {
+		fmt.Fprintf(w, "%s: %s\n", color.RedString("Outputs Error"), err)
+		return
+	}
+	if len(outs) > 0 {
+		fmt.Fprintf(w, "Outputs:\n")
+		for _, k := range sortedKeys(outs) {
+			fmt.Fprintf(w, "  %s: %#v\n", k, outs[k])
+		}
+	}
+}
+
+// stepError prints the error of the step and fails it
+func (j *Job) stepError(w io.Writer, i int, name string, sr *StepResult, err error) {
+	num := color.HiBlackString(fmt.Sprintf("%2d.", i))
+	// 7 spaces
+	fmt.Fprintf(w, "%s %s %s\n       error: %s", num, color.RedString("✘ "), name, err)
+	var ae *ActionError
+	if errors.As(err, &ae) {
+		sr.ActionError = ae
+		if ae.Retryable {
+			fmt.Fprintf(w, " %s", color.HiBlackString("(retryable)"))
+		}
+	}
+	fmt.Fprintln(w)
+	sr.fail(err.Error())
+	j.ctx.SetFailed()
+}
+
+// hasChecks reports whether the step checks the response other than test
+func (st Step) hasChecks() bool {
+	return len(st.Expect) > 0 || st.Schema != nil || st.Snapshot.Enabled()
+}
+
+func (j *Job) skippedResult() *JobResult {
+	result := &JobResult{Name: j.Name, Status: StatusSkipped, Reason: j.skipReason}
+	for i, st := range j.Steps {
+		result.Steps = append(result.Steps, &StepResult{Index: i, Name: st.Name, Uses: st.Uses, Test: st.Test, Status: StatusSkipped})
+	}
+	return result
 }
 
 func NewTestContext(j JobContext, req, res map[string]any) TestContext {
 	return TestContext{
-		Envs: j.Envs,
-		Logs: j.Logs,
-		Req:  req,
-		Res:  res,
+		Envs:    j.Envs,
+		Vars:    j.Vars,
+		Secrets: j.Secrets,
+		Logs:    j.Logs,
+		Outputs: j.Outputs,
+		Req:     req,
+		Res:     res,
 	}
 }
 
-func showVerbose(i int, name string, req, res map[string]any) {
-	fmt.Printf("--- Step %d: %s\nRequest:\n", i, name)
+func showVerbose(w io.Writer, i int, name string, req, res map[string]any) {
+	fmt.Fprintf(w, "--- Step %d: %s\nRequest:\n", i, name)
 
 	for k, v := range req {
 		nested, ok := v.(map[string]any)
 		if ok {
-			fmt.Printf("  %s:\n", k)
+			fmt.Fprintf(w, "  %s:\n", k)
 			for kk, vv := range nested {
-				fmt.Printf("    %s: %#v\n", kk, vv)
+				fmt.Fprintf(w, "    %s: %#v\n", kk, vv)
 			}
 		} else {
-			fmt.Printf("  %s: %#v\n", k, v)
+			fmt.Fprintf(w, "  %s: %#v\n", k, v)
 		}
 	}
-	fmt.Printf("Response:\n")
+	fmt.Fprintf(w, "Response:\n")
 
 	for k, v := range res {
 		nested, ok := v.(map[string]any)
 		if ok {
-			fmt.Printf("  %s:\n", k)
+			fmt.Fprintf(w, "  %s:\n", k)
 			for kk, vv := range nested {
-				fmt.Printf("    %s: %#v\n", kk, vv)
+				fmt.Fprintf(w, "    %s: %#v\n", kk, vv)
 			}
 		} else {
-			fmt.Printf("  %s: %#v\n", k, v)
+			fmt.Fprintf(w, "  %s: %#v\n", k, v)
 		}
 	}
 }